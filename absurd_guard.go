@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+var ErrAbsurdKeywordNotFound = errors.New("keyword tidak ditemukan")
+
+// defaultPromptSuggestions dipakai jika saran dari rag_sql_examples tidak tersedia.
+var defaultPromptSuggestions = []string{
+	"ada berapa orang penabung saat ini",
+	"nasabah yang jenis tabungan nya deposito",
+}
+
+type AbsurdKeyword struct {
+	ID        int64     `json:"id"`
+	Keyword   string    `json:"keyword"`
+	Category  string    `json:"category,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AbsurdKeywordInput struct {
+	Keyword  string `json:"keyword"`
+	Category string `json:"category"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// PromptVerdict adalah hasil pemeriksaan apakah prompt absurd / di luar topik.
+type PromptVerdict struct {
+	Absurd          bool     `json:"absurd"`
+	Reason          string   `json:"reason,omitempty"` // "keyword" atau "off_topic"
+	MatchedKeywords []string `json:"matched_keywords,omitempty"`
+	TopScore        float32  `json:"top_score,omitempty"`
+	Suggestions     []string `json:"suggestions,omitempty"`
+	// Vector adalah embedding prompt bila classifier sempat menghitungnya; dipakai ulang oleh
+	// pipeline SQL agar prompt tidak di-embed dua kali.
+	Vector []float32 `json:"-"`
+}
+
+type absurdMatcher struct {
+	ac       *ahoCorasick
+	loadedAt time.Time
+}
+
+var (
+	absurdMatcherMu  sync.RWMutex
+	absurdMatcherCur *absurdMatcher
+)
+
+const absurdKeywordColumns = `
+	id,
+	keyword,
+	COALESCE(category, ''),
+	is_active,
+	updated_at`
+
+func scanAbsurdKeyword(row rowScanner) (AbsurdKeyword, error) {
+	var k AbsurdKeyword
+	err := row.Scan(&k.ID, &k.Keyword, &k.Category, &k.IsActive, &k.UpdatedAt)
+	return k, err
+}
+
+func ListAbsurdKeywords(ctx context.Context, activeOnly bool) ([]AbsurdKeyword, error) {
+	if DbInstance == nil {
+		return nil, fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	rows, err := DbInstance.QueryContext(ctx, fmt.Sprintf(`
+	SELECT %s
+	FROM
+		%s
+	WHERE
+		NOT $1 OR is_active
+	ORDER BY
+		keyword;
+	`, absurdKeywordColumns, metaTable("absurd_keywords")), activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca absurd_keywords: %w", err)
+	}
+	defer rows.Close()
+
+	keywords := make([]AbsurdKeyword, 0)
+	for rows.Next() {
+		k, err := scanAbsurdKeyword(rows)
+		if err != nil {
+			return nil, err
+		}
+		keywords = append(keywords, k)
+	}
+	return keywords, rows.Err()
+}
+
+func validateAbsurdKeywordInput(in *AbsurdKeywordInput) error {
+	in.Keyword = strings.TrimSpace(in.Keyword)
+	in.Category = strings.TrimSpace(in.Category)
+	if normalizePromptKey(in.Keyword) == "" {
+		return &ValidationError{Reason: "keyword wajib diisi dan harus mengandung huruf/angka"}
+	}
+	return nil
+}
+
+func CreateAbsurdKeyword(ctx context.Context, in AbsurdKeywordInput) (AbsurdKeyword, error) {
+	if err := validateAbsurdKeywordInput(&in); err != nil {
+		return AbsurdKeyword{}, err
+	}
+	active := in.IsActive == nil || *in.IsActive
+
+	k, err := scanAbsurdKeyword(DbInstance.QueryRowContext(ctx, fmt.Sprintf(`
+	INSERT INTO %s
+		(keyword, category, is_active)
+	VALUES
+		($1, NULLIF($2, ''), $3)
+	RETURNING %s
+	`, metaTable("absurd_keywords"), absurdKeywordColumns), in.Keyword, in.Category, active))
+	if err != nil {
+		return k, fmt.Errorf("gagal menyimpan keyword: %w", err)
+	}
+	reloadAbsurdMatcherAfterChange(ctx)
+	return k, nil
+}
+
+func UpdateAbsurdKeyword(ctx context.Context, id int64, in AbsurdKeywordInput) (AbsurdKeyword, error) {
+	if err := validateAbsurdKeywordInput(&in); err != nil {
+		return AbsurdKeyword{}, err
+	}
+
+	k, err := scanAbsurdKeyword(DbInstance.QueryRowContext(ctx, fmt.Sprintf(`
+	UPDATE %s
+	SET
+		keyword = $1,
+		category = NULLIF($2, ''),
+		is_active = COALESCE($3, is_active),
+		updated_at = now()
+	WHERE
+		id = $4
+	RETURNING %s
+	`, metaTable("absurd_keywords"), absurdKeywordColumns), in.Keyword, in.Category, in.IsActive, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, ErrAbsurdKeywordNotFound
+	}
+	if err != nil {
+		return k, fmt.Errorf("gagal memperbarui keyword: %w", err)
+	}
+	reloadAbsurdMatcherAfterChange(ctx)
+	return k, nil
+}
+
+func DeleteAbsurdKeyword(ctx context.Context, id int64) error {
+	res, err := DbInstance.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", metaTable("absurd_keywords")), id)
+	if err != nil {
+		return fmt.Errorf("gagal menghapus keyword: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAbsurdKeywordNotFound
+	}
+	reloadAbsurdMatcherAfterChange(ctx)
+	return nil
+}
+
+func reloadAbsurdMatcherAfterChange(ctx context.Context) {
+	if _, err := ReloadAbsurdMatcher(ctx); err != nil {
+		log.Printf("PERINGATAN: Gagal memuat ulang matcher absurd_keywords: %v", err)
+	}
+}
+
+// ReloadAbsurdMatcher membangun ulang automaton Aho-Corasick dari keyword aktif.
+func ReloadAbsurdMatcher(ctx context.Context) (*absurdMatcher, error) {
+	keywords, err := ListAbsurdKeywords(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		patterns = append(patterns, normalizePromptKey(k.Keyword))
+	}
+
+	m := &absurdMatcher{ac: newAhoCorasick(patterns), loadedAt: time.Now()}
+	absurdMatcherMu.Lock()
+	absurdMatcherCur = m
+	absurdMatcherMu.Unlock()
+
+	log.Printf("✅ Matcher absurd_keywords dimuat: %d keyword aktif.", len(patterns))
+	return m, nil
+}
+
+// currentAbsurdMatcher mengembalikan matcher aktif; dimuat ulang bila belum ada atau sudah
+// lebih lama dari ABSURD_KEYWORDS_RELOAD_SECONDS (agar perubahan dari instance lain ikut terbaca).
+func currentAbsurdMatcher(ctx context.Context) (*absurdMatcher, error) {
+	absurdMatcherMu.RLock()
+	m := absurdMatcherCur
+	absurdMatcherMu.RUnlock()
+
+	if m != nil && (AppConfig.AbsurdReloadInterval <= 0 || time.Since(m.loadedAt) < AppConfig.AbsurdReloadInterval) {
+		return m, nil
+	}
+
+	fresh, err := ReloadAbsurdMatcher(ctx)
+	if err != nil {
+		if m != nil {
+			log.Printf("PERINGATAN: Gagal memuat ulang absurd_keywords, memakai matcher lama: %v", err)
+			return m, nil
+		}
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// ClassifyPrompt memeriksa prompt dengan matcher keyword (kata utuh) dan, bila diaktifkan,
+// classifier embedding: prompt dianggap di luar topik jika tidak ada isi collection RAG yang
+// cukup mirip. Untuk prompt yang ditolak, saran diambil dari prompt rag_sql_examples terdekat.
+func ClassifyPrompt(ctx context.Context, prompt string) (PromptVerdict, error) {
+	var verdict PromptVerdict
+	if AppConfig == nil {
+		return verdict, fmt.Errorf("konfigurasi aplikasi belum dimuat")
+	}
+
+	m, err := currentAbsurdMatcher(ctx)
+	if err != nil {
+		return verdict, err
+	}
+	if matched := m.ac.MatchWords(normalizePromptKey(prompt)); len(matched) > 0 {
+		verdict.Absurd = true
+		verdict.Reason = "keyword"
+		verdict.MatchedKeywords = matched
+	}
+
+	var promptVector []float32
+	needVector := verdict.Absurd || AppConfig.OffTopicClassifierEnabled
+	if needVector {
+		promptVector, err = GenerateEmbedding(ctx, prompt)
+		if err != nil {
+			log.Printf("PERINGATAN: Gagal embed prompt untuk classifier: %v", err)
+		}
+		verdict.Vector = promptVector
+	}
+
+	if !verdict.Absurd && AppConfig.OffTopicClassifierEnabled && promptVector != nil {
+		topScore, err := topRAGScore(ctx, promptVector)
+		if err != nil {
+			log.Printf("PERINGATAN: Classifier off-topic dilewati: %v", err)
+		} else {
+			verdict.TopScore = topScore
+			if topScore < AppConfig.OffTopicMinScore {
+				verdict.Absurd = true
+				verdict.Reason = "off_topic"
+			}
+		}
+	}
+
+	if verdict.Absurd {
+		log.Printf("Prompt terdeteksi absurd (%s): '%s'", verdict.Reason, prompt)
+		verdict.Suggestions = NearestExamplePrompts(ctx, prompt, promptVector, AppConfig.SuggestionCount)
+	}
+	return verdict, nil
+}
+
+func topRAGScore(ctx context.Context, promptVector []float32) (float32, error) {
+	var limit uint64 = 1
+	points, err := qdrantQuery(ctx, &pb.QueryPoints{
+		CollectionName: ragReadCollection(),
+		Query:          pb.NewQuery(promptVector...),
+		Limit:          &limit,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 {
+		return 0, nil
+	}
+	return points[0].Score, nil
+}
+
+// NearestExamplePrompts mengambil prompt rag_sql_examples yang paling dekat dengan prompt,
+// lewat embedding (Qdrant) atau BM25 jika vektor tidak tersedia.
+func NearestExamplePrompts(ctx context.Context, prompt string, promptVector []float32, limit int) []string {
+	if limit <= 0 {
+		limit = 3
+	}
+
+	var suggestions []string
+	seen := make(map[string]bool)
+	add := func(s string) {
+		s = strings.TrimSpace(s)
+		key := normalizePromptKey(s)
+		if s == "" || seen[key] || len(suggestions) >= limit {
+			return
+		}
+		seen[key] = true
+		suggestions = append(suggestions, s)
+	}
+
+	if promptVector != nil && qdrantClient != nil {
+		searchLimit := uint64(limit * 2)
+		points, err := qdrantQuery(ctx, &pb.QueryPoints{
+			CollectionName: ragReadCollection(),
+			Query:          pb.NewQuery(promptVector...),
+			WithPayload:    pb.NewWithPayload(true),
+			Limit:          &searchLimit,
+			Filter:         categoryFilter("sql"),
+		})
+		if err != nil {
+			log.Printf("PERINGATAN: Gagal mencari contoh terdekat untuk saran: %v", err)
+		}
+		for _, p := range points {
+			if v, ok := p.GetPayload()["prompt_preview"]; ok {
+				add(v.GetStringValue())
+			}
+		}
+	}
+
+	if len(suggestions) < limit {
+		for _, c := range searchSparseExamples(prompt, limit*2) {
+			firstLine := strings.SplitN(c.Content, "\n", 2)[0]
+			add(cleanExamplePrompt(firstLine))
+		}
+	}
+
+	if len(suggestions) == 0 {
+		return defaultPromptSuggestions
+	}
+	return suggestions
+}
@@ -0,0 +1,105 @@
+package main
+
+// ahoCorasick mencocokkan banyak keyword sekaligus dalam satu kali scan teks.
+// Teks dan pola diharapkan sudah dinormalisasi dengan normalizePromptKey
+// (huruf kecil, hanya huruf/angka dipisah satu spasi), sehingga batas kata = spasi.
+type ahoCorasick struct {
+	nodes    []acNode
+	patterns []string
+}
+
+type acNode struct {
+	next   map[rune]int
+	fail   int
+	output []int // indeks pola yang berakhir di node ini (termasuk lewat fail link)
+}
+
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{next: map[rune]int{}}}}
+
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		idx := len(ac.patterns)
+		ac.patterns = append(ac.patterns, p)
+
+		cur := 0
+		for _, r := range p {
+			nxt, ok := ac.nodes[cur].next[r]
+			if !ok {
+				nxt = len(ac.nodes)
+				ac.nodes = append(ac.nodes, acNode{next: map[rune]int{}})
+				ac.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+		}
+		ac.nodes[cur].output = append(ac.nodes[cur].output, idx)
+	}
+
+	// BFS untuk membangun fail link
+	queue := make([]int, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range ac.nodes[cur].next {
+			f := ac.nodes[cur].fail
+			for f != 0 {
+				if _, ok := ac.nodes[f].next[r]; ok {
+					break
+				}
+				f = ac.nodes[f].fail
+			}
+			if target, ok := ac.nodes[f].next[r]; ok && target != child {
+				ac.nodes[child].fail = target
+			}
+			ac.nodes[child].output = append(ac.nodes[child].output, ac.nodes[ac.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+// MatchWords mengembalikan pola yang muncul sebagai kata/frasa utuh di text.
+func (ac *ahoCorasick) MatchWords(text string) []string {
+	if ac == nil || len(ac.patterns) == 0 {
+		return nil
+	}
+
+	runes := []rune(text)
+	seen := make(map[int]bool)
+	var matches []string
+
+	cur := 0
+	for i, r := range runes {
+		for cur != 0 {
+			if _, ok := ac.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = ac.nodes[cur].fail
+		}
+		if nxt, ok := ac.nodes[cur].next[r]; ok {
+			cur = nxt
+		}
+
+		for _, idx := range ac.nodes[cur].output {
+			if seen[idx] {
+				continue
+			}
+			length := len([]rune(ac.patterns[idx]))
+			start := i - length + 1
+			if start > 0 && runes[start-1] != ' ' {
+				continue
+			}
+			if i+1 < len(runes) && runes[i+1] != ' ' {
+				continue
+			}
+			seen[idx] = true
+			matches = append(matches, ac.patterns[idx])
+		}
+	}
+	return matches
+}
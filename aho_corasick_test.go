@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAhoCorasickMatchWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     []string
+	}{
+		{"kata utuh di tengah", []string{"judi"}, "cara main judi online", []string{"judi"}},
+		{"awal dan akhir teks", []string{"resep", "cuaca"}, "resep rendang dan cuaca", []string{"cuaca", "resep"}},
+		{"bukan kata utuh (prefix)", []string{"judi"}, "judikatif", nil},
+		{"bukan kata utuh (suffix)", []string{"bola"}, "sepakbola", nil},
+		{"frasa beberapa kata", []string{"main game"}, "ayo main game sekarang", []string{"main game"}},
+		{"frasa terpotong", []string{"main game"}, "main gamelan", nil},
+		{"pola tumpang tindih", []string{"he", "she", "hers"}, "she hers he", []string{"he", "hers", "she"}},
+		{"pola lebih pendek di dalam pola lain", []string{"bola", "sepak bola"}, "nonton sepak bola", []string{"bola", "sepak bola"}},
+		{"lewat fail link setelah gagal cocok", []string{"abcd", "bc"}, "abc bc", []string{"bc"}},
+		{"teks sama dengan pola", []string{"togel"}, "togel", []string{"togel"}},
+		{"muncul berulang dilaporkan sekali", []string{"slot"}, "slot slot slot", []string{"slot"}},
+		{"pola kosong diabaikan", []string{"", "zodiak"}, "zodiak hari ini", []string{"zodiak"}},
+		{"tanpa pola", nil, "apa saja", nil},
+		{"unicode", []string{"café"}, "ngopi di café dekat", []string{"café"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newAhoCorasick(tt.patterns).MatchWords(tt.text)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchWords(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAhoCorasickNil(t *testing.T) {
+	var ac *ahoCorasick
+	if got := ac.MatchWords("apa saja"); got != nil {
+		t.Errorf("MatchWords pada matcher nil = %v, want nil", got)
+	}
+}
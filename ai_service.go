@@ -1,16 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -47,9 +43,12 @@ func InitVectorService() error {
 
 	log.Printf("Memastikan collection cache '%s' ada via REST...", AppConfig.QdrantCacheCollection)
 
-	if err := qdrantCreateCollection(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection,
-		AppConfig.EmbeddingVectorSize, AppConfig.QdrantDistanceMetric); err != nil {
-		return fmt.Errorf("gagal membuat/memverifikasi cache collection: %w", err)
+	// Qdrant yang tidak tersedia tidak menghentikan startup: layanan berjalan dalam mode degraded
+	// dan StartVectorReconnector menghubungkan ulang di background.
+	if err := connectQdrant(ctx); err != nil {
+		markVectorDegraded(err)
+		log.Printf("PERINGATAN: Qdrant belum tersedia, layanan berjalan dalam mode degraded: %v", err)
+		return nil
 	}
 
 	log.Println("✅ Berhasil terkoneksi ke Layanan Vektor (Google AI & Qdrant).")
@@ -66,14 +65,68 @@ type GroqMessage struct {
 	Content string `json:"content"`
 }
 type GroqRequest struct {
-	Model       string        `json:"model"`
-	Messages    []GroqMessage `json:"messages"`
-	Temperature float32       `json:"temperature"`
+	Model          string              `json:"model"`
+	Messages       []GroqMessage       `json:"messages"`
+	Temperature    float32             `json:"temperature"`
+	ResponseFormat *groqResponseFormat `json:"response_format,omitempty"`
 }
 type GroqResponse struct {
 	Choices []struct {
 		Message GroqMessage `json:"message"`
 	} `json:"choices"`
+	Usage llmUsage `json:"usage"`
+}
+
+// categoryFilter membatasi pencarian RAG ke satu kategori payload ("sql" atau "ddl").
+func categoryFilter(category string) *pb.Filter {
+	return &pb.Filter{
+		Must: []*pb.Condition{
+			{
+				ConditionOneOf: &pb.Condition_Field{
+					Field: &pb.FieldCondition{
+						Key: "category",
+						Match: &pb.Match{
+							MatchValue: &pb.Match_Keyword{
+								Keyword: category,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// qdrantQuery menjalankan Query gRPC Qdrant lewat retry & circuit breaker dependency qdrant.
+// Selama mode degraded panggilan langsung gagal dengan ErrVectorDegraded.
+func qdrantQuery(ctx context.Context, req *pb.QueryPoints) ([]*pb.ScoredPoint, error) {
+	if VectorServiceDegraded() {
+		return nil, ErrVectorDegraded
+	}
+	var points []*pb.ScoredPoint
+	err := resilientCall(ctx, depQdrant, func(ctx context.Context) callOutcome {
+		var err error
+		points, err = qdrantClient.Query(ctx, req)
+		return grpcOutcome(err)
+	})
+	noteVectorResult(err)
+	return points, err
+}
+
+// qdrantScroll menjalankan Scroll gRPC Qdrant lewat retry & circuit breaker dependency qdrant.
+// Selama mode degraded panggilan langsung gagal dengan ErrVectorDegraded.
+func qdrantScroll(ctx context.Context, req *pb.ScrollPoints) ([]*pb.RetrievedPoint, error) {
+	if VectorServiceDegraded() {
+		return nil, ErrVectorDegraded
+	}
+	var points []*pb.RetrievedPoint
+	err := resilientCall(ctx, depQdrant, func(ctx context.Context) callOutcome {
+		var err error
+		points, err = qdrantClient.Scroll(ctx, req)
+		return grpcOutcome(err)
+	})
+	noteVectorResult(err)
+	return points, err
 }
 
 func sanitizeSQL(sql string) string {
@@ -104,41 +157,97 @@ func sanitizeSQL(sql string) string {
 	return cleanSql
 }
 
-func getSQLFromAI_Groq(userPrompt string) (AISqlResponse, error) {
+// clarificationInstructions menjelaskan kapan LLM harus meminta klarifikasi alih-alih menebak.
+func clarificationInstructions() string {
+	if AppConfig == nil || !AppConfig.ClarificationEnabled {
+		return ""
+	}
+	return `
+== KAPAN MEMINTA KLARIFIKASI ==
+Jika pertanyaan TIDAK BISA dijawab dengan tepat tanpa informasi tambahan, JANGAN menebak dan JANGAN menulis SQL. Contoh:
+- Periode waktu tidak disebut padahal yang diminta mutasi/transaksi/saldo pada waktu tertentu.
+- Jenis rekening/produk tidak jelas (tabungan, deposito, giro) padahal hasilnya berbeda.
+- Nama nasabah disebut tanpa CIF/nomor rekening dan bisa cocok dengan lebih dari satu nasabah.
+Dalam kasus tersebut, kosongkan field "sql" dan isi field "clarification" dengan:
+{"reason": "alasan singkat", "questions": ["pertanyaan lanjutan yang spesifik"], "rephrasings": ["contoh pertanyaan lengkap yang bisa langsung dijawab"]}
+`
+}
+
+func getSQLFromAI_Groq(ctx context.Context, userPrompt string, opts SQLGenOptions) (AISqlResponse, error) {
 	if AppConfig == nil {
 		return AISqlResponse{}, fmt.Errorf("konfigurasi aplikasi belum dimuat")
 	}
 
-	ctx := context.Background()
-
-	log.Println("Menerjemahkan prompt user ke vektor...")
-	res, err := geminiEmbedder.EmbedContent(ctx, genai.Text(userPrompt))
+	promptTmpl, err := SelectPromptTemplate(opts.PromptVersion, userPrompt)
 	if err != nil {
-		return AISqlResponse{}, fmt.Errorf("gagal embed prompt user: %w", err)
+		return AISqlResponse{}, err
 	}
-	promptVector := res.Embedding.Values
 
-	log.Println("Mencari di Semantic Cache Qdrant (REST)...")
-
-	searchReq := qdrantSearchReq{
-		Vector:      promptVector,
-		Limit:       AppConfig.CacheSearchLimit,
-		WithPayload: true,
+	var stageStart time.Time
+	var stageCtx context.Context
+	var cancel context.CancelFunc
+	promptVector := opts.PromptVector
+	if len(promptVector) == 0 {
+		log.Println("Menerjemahkan prompt user ke vektor...")
+		stageStart = time.Now()
+		stageCtx, cancel = stageContext(ctx, budgetShareEmbedding)
+		promptVector, err = GenerateEmbedding(stageCtx, userPrompt)
+		cancel()
+		if err != nil {
+			return AISqlResponse{}, fmt.Errorf("gagal embed prompt user: %w", err)
+		}
+		opts.Timings.observe("embedding", stageStart)
 	}
 
-	cacheResponse, err := qdrantSearchPoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection, searchReq)
-	if err != nil {
-		log.Printf("PERINGATAN: Gagal mencari di cache Qdrant: %v", err)
+	var cacheResponse qdrantSearchResp
+	if opts.SkipCache {
+		log.Println("Semantic cache dilewati (SkipCache).")
+	} else if VectorServiceDegraded() {
+		log.Println("⚠️ Mode degraded: semantic cache dilewati.")
+	} else {
+		log.Println("Mencari di Semantic Cache Qdrant (REST)...")
+
+		searchReq := qdrantSearchReq{
+			Vector:      promptVector,
+			Limit:       AppConfig.CacheSearchLimit,
+			WithPayload: true,
+		}
+
+		stageStart = time.Now()
+		stageCtx, cancel = stageContext(ctx, budgetShareCacheLookup)
+		cacheResponse, err = qdrantSearchPoints(stageCtx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection, searchReq)
+		cancel()
+		opts.Timings.observe("cache_lookup", stageStart)
+		noteVectorResult(err)
+		if err != nil {
+			log.Printf("PERINGATAN: Gagal mencari di cache Qdrant: %v", err)
+		}
 	}
 
+	var topCacheScore float32
 	if len(cacheResponse.Result) > 0 {
 		cachedPoint := cacheResponse.Result[0]
 		topScore := cachedPoint.Score
+		topCacheScore = topScore
 
-		if topScore >= AppConfig.CacheSimilarityThreshold {
+		cachedVersion, _ := cachedPoint.Payload["schema_version"].(string)
+		currentVersion := CurrentSchemaVersion()
+		cachedPromptVersion, _ := cachedPoint.Payload["prompt_version"].(string)
+
+		if topScore >= AppConfig.CacheSimilarityThreshold && cachedVersion != "" && currentVersion != "" && cachedVersion != currentVersion {
+			log.Printf("CACHE MISS. Item cache (Skor: %f) dibuat untuk versi skema %s, versi aktif %s.", topScore, cachedVersion, currentVersion)
+		} else if topScore >= AppConfig.CacheSimilarityThreshold && opts.PromptVersion != "" && cachedPromptVersion != opts.PromptVersion {
+			log.Printf("CACHE MISS. Item cache (Skor: %f) dibuat dengan template prompt '%s', diminta '%s'.", topScore, cachedPromptVersion, opts.PromptVersion)
+		} else if topScore >= AppConfig.CacheSimilarityThreshold {
 			if cachedSql, ok := cachedPoint.Payload["sql_query"]; ok {
 				log.Printf("✅ SEMANTIC CACHE HIT! Skor: %f (Melebihi Threshold: %f)", topScore, AppConfig.CacheSimilarityThreshold)
-				return AISqlResponse{SQL: cachedSql.(string), IsCached: true}, nil
+				return AISqlResponse{
+					SQL:           cachedSql.(string),
+					IsCached:      true,
+					PromptVersion: cachedPromptVersion,
+					Source:        SQLSourceCache,
+					CacheScore:    topScore,
+				}, nil
 			} else {
 				log.Printf("CACHE MISS. Ditemukan item cache (Skor: %f) tapi payload 'sql_query' hilang.", topScore)
 			}
@@ -152,238 +261,214 @@ func getSQLFromAI_Groq(userPrompt string) (AISqlResponse, error) {
 	log.Println("Memanggil RAG (gRPC) + Groq AI...")
 	log.Println("Mencari konteks relevan di Qdrant (RAG)...")
 
-	var searchLimit uint64 = 10
+	searchLimit := AppConfig.RAGSearchLimit
 
-	searchResponse, err := qdrantClient.Query(ctx, &pb.QueryPoints{
-		CollectionName: AppConfig.QdrantCollectionName,
+	stageStart = time.Now()
+	stageCtx, cancel = stageContext(ctx, budgetShareRAGSearch)
+	searchResponse, err := qdrantQuery(stageCtx, &pb.QueryPoints{
+		CollectionName: ragReadCollection(),
 		Query:          pb.NewQuery(promptVector...),
 		WithPayload:    pb.NewWithPayload(true),
 		Limit:          &searchLimit,
-		Filter: &pb.Filter{
-			Must: []*pb.Condition{
-				{
-					ConditionOneOf: &pb.Condition_Field{
-						Field: &pb.FieldCondition{
-							Key: "category",
-							Match: &pb.Match{
-								MatchValue: &pb.Match_Keyword{
-									Keyword: "sql",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+		Filter:         categoryFilter("sql"),
 	})
-	if err != nil {
+	cancel()
+	// Qdrant tidak tersedia: lanjut zero-shot dengan DDL + contoh dari indeks BM25 lokal
+	degraded := err != nil && VectorServiceDegraded()
+	if err != nil && !degraded {
 		return AISqlResponse{}, fmt.Errorf("gagal mencari RAG di Qdrant: %w", err)
 	}
-	const SimilarityConfidenceThreshold = 0.45
-	var sqlContext string
-	if len(searchResponse) > 0 {
-		topResult := searchResponse[0]
-		log.Printf("🔍 Top RAG Score: %f", topResult.Score)
-		if topResult.Score < SimilarityConfidenceThreshold {
-			log.Println("⚠️ Score RAG rendah. Mengabaikan contoh RAG, beralih ke mode Zero-Shot dengan DDL & Referensi.")
-			sqlContext = "TIDAK ADA CONTOH SQL YANG RELEVAN. GUNAKAN LOGIKA ANDA SENDIRI BERDASARKAN DDL DAN DATA REFERENSI."
-		} else {
-			// Jika score bagus, rakit contekan
-			var contextBuilder strings.Builder
-			contextBuilder.WriteString("Berikut adalah CONTOH DDL dan SQL yang paling relevan (IKUTI POLA INI):\n")
-
-			seenContents := make(map[string]bool)
-			for _, point := range searchResponse {
-				if p := point.GetPayload(); p != nil {
-					if v, ok := p["content"]; ok {
-						contekan := v.GetStringValue()
-						if contekan != "" && !seenContents[contekan] {
-							seenContents[contekan] = true
-							contextBuilder.WriteString(contekan)
-							contextBuilder.WriteString("\n---\n")
-						}
-					}
-				}
+	if degraded {
+		log.Printf("⚠️ Mode degraded: RAG Qdrant dilewati, memakai indeks contoh lokal (BM25).")
+	}
+
+	var denseCandidates []ragCandidate
+	for _, point := range searchResponse {
+		if p := point.GetPayload(); p != nil {
+			if v, ok := p["content"]; ok && v.GetStringValue() != "" {
+				denseCandidates = append(denseCandidates, ragCandidate{
+					ID:         pointIDString(point.GetId()),
+					Content:    v.GetStringValue(),
+					DenseScore: point.Score,
+				})
 			}
-			log.Println("✅ Konteks RAG (Contekan) berhasil dirakit.")
-			sqlContext = contextBuilder.String()
 		}
-	} else {
-		sqlContext = "TIDAK ADA CONTOH SQL. GUNAKAN LOGIKA ANDA SENDIRI BERDASARKAN DDL."
 	}
 
-	allDDLs, err := GetDynamicSchemaContext()
-	if err != nil {
-		return AISqlResponse{}, fmt.Errorf("gagal mengambil DDL dinamis: %w", err)
+	var sparseCandidates []ragCandidate
+	if AppConfig.RAGHybridEnabled || degraded {
+		sparseCandidates = searchSparseExamples(userPrompt, int(searchLimit))
+		log.Printf("🔎 BM25 (sparse) menemukan %d kandidat.", len(sparseCandidates))
 	}
-	allDDLString := strings.Join(allDDLs, "\n---\n")
 
-	refDataString, err := GetDynamicReferenceData(ctx)
-	if err != nil {
-		log.Println("Warning: Gagal ambil data referensi:", err)
-		refDataString = "(Data referensi tidak tersedia)"
+	var topDense float32
+	if len(denseCandidates) > 0 {
+		topDense = denseCandidates[0].DenseScore
+		log.Printf("🔍 Top RAG Score: %f", topDense)
+	}
+	var topSparse float64
+	if len(sparseCandidates) > 0 {
+		topSparse = sparseCandidates[0].SparseScore
+		log.Printf("🔍 Top BM25 Score: %f", topSparse)
 	}
-	businessDict, err := GetBusinessDictionary(ctx)
-	if err != nil {
-		log.Println("Warning: Gagal ambil dictionary:", err)
-		businessDict = ""
-	}
-
-	finalPrompt := fmt.Sprintf(`
-Anda adalah ahli SQL PostgreSQL senior. Tanggal hari ini: %s.
-
-== 1. KAMUS DATA (DDL & STRUKTUR) ==
-Baca DDL ini dengan teliti. Perhatikan KOMENTAR (-- ...) di setiap kolom untuk memahami artinya.
-%s
-
-== 2. LIVE DATA REFERENSI (PENTING: JANGAN MENEBAK ID) ==
-Gunakan ID yang tertera di sini jika query membutuhkan filter berdasarkan Status, Tipe, atau Kategori.
-JANGAN MENGARANG ID SENDIRI.
-%s
-
-== 3. KAMUS ISTILAH BISNIS ==
-%s
-
-== 4. CONTOH SQL (RAG CONTEXT) ==
-%s
-
-== ATURAN PENULISAN SQL (ZERO-SHOT & RAG) ==
-1. **Priority Reference**: Jika user menyebut "Tabungan", "Deposito", "Aktif", atau "Tutup", WAJIB cek bagian "LIVE DATA REFERENSI" untuk mendapatkan ID yang tepat. Jangan menebak "1" atau "0".
-2. **Column Validation**: Hanya gunakan kolom yang ADA di DDL di atas.
-3. **Security**: Hanya SELECT. Dilarang INSERT/UPDATE/DELETE.
-
-== TUGAS ANDA (CHAIN OF THOUGHT) ==
-Sebelum menulis kode SQL, jelaskan langkah berpikir Anda secara singkat:
-1. **Analisis Intent**: Apa data yang dicari user?
-2. **Mapping Referensi**: Apakah ada kata kunci (misal: "blokir") yang perlu dicari ID-nya di "LIVE DATA REFERENSI"? Jika ada, sebutkan ID-nya.
-3. **Strategi Query**: Table mana yang di-JOIN? Apa kondisi WHERE-nya?
-4. **SQL Final**: Tulis query dalam blok markdown code.
-
-Pertanyaan Pengguna: "%s"
-`,
-		time.Now().Format("2006-01-02"),
-		allDDLString,
-		refDataString,
-		businessDict,
-		sqlContext,
-		userPrompt,
-	)
-
-	var rawContent string
-	var ollamaSuccess bool = false
-
-	if AppConfig != nil && AppConfig.OllamaURL != "" {
-		log.Printf("🔄 Mencoba Ollama LLM lokal: %s (model=%s)", AppConfig.OllamaURL, AppConfig.OllamaModel)
-
-		ollamaReq := map[string]any{
-			"model":  AppConfig.OllamaModel,
-			"prompt": finalPrompt,
-			"stream": false,
-		}
-
-		resp, respBodyBytes, errOllama := httpDoJSON(ctx, "POST", strings.TrimRight(AppConfig.OllamaURL, "/")+"/api/generate", ollamaReq)
 
-		if errOllama != nil {
-			log.Printf("⚠️ Gagal koneksi ke Ollama: %v. Akan beralih ke Groq.", errOllama)
-		} else if resp.StatusCode != http.StatusOK {
-			log.Printf("⚠️ Ollama error status %d: %s. Akan beralih ke Groq.", resp.StatusCode, string(respBodyBytes))
-		} else {
-			var ollamaResp map[string]any
-			if err := json.Unmarshal(respBodyBytes, &ollamaResp); err == nil {
-				if r, ok := ollamaResp["response"].(string); ok {
-					rawContent = r
-				} else if t, ok := ollamaResp["text"].(string); ok {
-					rawContent = t
-				} else if gens, ok := ollamaResp["generations"].([]any); ok && len(gens) > 0 {
-					if first, ok := gens[0].(map[string]any); ok {
-						if c, ok := first["content"].(string); ok {
-							rawContent = c
-						}
-					}
-				}
+	var sqlContext string
+	var ragExamples []RAGExampleRef
+	source := SQLSourceRAG
+	if len(denseCandidates) == 0 && len(sparseCandidates) == 0 {
+		source = SQLSourceZeroShot
+		sqlContext = "TIDAK ADA CONTOH SQL. GUNAKAN LOGIKA ANDA SENDIRI BERDASARKAN DDL."
+	} else if (degraded || topDense < AppConfig.RAGMinDenseScore) && topSparse < float64(AppConfig.RAGSparseMinScore) {
+		source = SQLSourceZeroShot
+		ragExamples = append(ragExampleRefs(denseCandidates, false), ragExampleRefs(sparseCandidates, false)...)
+		log.Println("⚠️ Score RAG rendah. Mengabaikan contoh RAG, beralih ke mode Zero-Shot dengan DDL & Referensi.")
+		sqlContext = "TIDAK ADA CONTOH SQL YANG RELEVAN. GUNAKAN LOGIKA ANDA SENDIRI BERDASARKAN DDL DAN DATA REFERENSI."
+	} else {
+		// Jika score bagus, rakit contekan (hasil fusi dense + sparse bila hybrid aktif)
+		candidates := denseCandidates
+		if degraded {
+			candidates = sparseCandidates
+			if limit := int(AppConfig.RAGSearchLimit); limit > 0 && len(candidates) > limit {
+				candidates = candidates[:limit]
 			}
+		} else if AppConfig.RAGHybridEnabled {
+			candidates = fuseRRF(denseCandidates, sparseCandidates,
+				float64(AppConfig.RAGDenseWeight), float64(AppConfig.RAGSparseWeight),
+				AppConfig.RAGRRFK, int(AppConfig.RAGSearchLimit))
+		}
 
-			if rawContent != "" {
-				ollamaSuccess = true
-				log.Println("✅ Sukses mendapatkan respon dari Ollama.")
-			} else {
-				log.Println("⚠️ Respon Ollama kosong/format salah. Beralih ke Groq...")
+		var contextBuilder strings.Builder
+		contextBuilder.WriteString("Berikut adalah CONTOH DDL dan SQL yang paling relevan (IKUTI POLA INI):\n")
+
+		seenContents := make(map[string]bool)
+		for _, c := range candidates {
+			if !seenContents[c.Content] {
+				seenContents[c.Content] = true
+				contextBuilder.WriteString(c.Content)
+				contextBuilder.WriteString("\n---\n")
 			}
 		}
+		log.Println("✅ Konteks RAG (Contekan) berhasil dirakit.")
+		sqlContext = contextBuilder.String()
+		ragExamples = ragExampleRefs(candidates, true)
 	}
+	opts.Timings.observe("rag_search", stageStart)
 
-	if !ollamaSuccess {
-		log.Println("Menggunakan Layanan Groq AI...")
+	stageStart = time.Now()
+	stageCtx, cancel = stageContext(ctx, budgetSharePromptBuild)
+	defer cancel()
+	snap, err := CurrentSchemaSnapshot(stageCtx)
+	if err != nil {
+		return AISqlResponse{}, fmt.Errorf("gagal mengambil DDL dinamis: %w", err)
+	}
 
-		groqReqBody := GroqRequest{
-			Model:       AppConfig.GroqModel,
-			Messages:    []GroqMessage{{Role: "user", Content: finalPrompt}},
-			Temperature: 0,
-		}
-		jsonBody, err := json.Marshal(groqReqBody)
-		if err != nil {
-			return AISqlResponse{}, err
+	relevantDDLs := GetRelevantSchemaContext(stageCtx, snap, promptVector)
+	allDDLString := strings.Join(relevantDDLs, "\n---\n")
+
+	refDataString := snap.ReferenceData
+	businessDict := renderBusinessDictionary(SelectRelevantDictionary(stageCtx, snap.Dictionary, userPrompt, promptVector))
+
+	finalPrompt, err := promptTmpl.Render(PromptTemplateData{
+		Today:                     time.Now().Format("2006-01-02"),
+		DDL:                       allDDLString,
+		ReferenceData:             refDataString,
+		BusinessDictionary:        businessDict,
+		SQLContext:                sqlContext,
+		ClarificationEnabled:      AppConfig.ClarificationEnabled,
+		ClarificationInstructions: clarificationInstructions(),
+		OutputFormat:              llmOutputInstructions(),
+		Question:                  userPrompt,
+	})
+	if err != nil {
+		return AISqlResponse{}, err
+	}
+	log.Printf("📝 Template prompt: %s (%s)", promptTmpl.Version, promptTmpl.Source)
+	opts.Timings.observe("prompt_build", stageStart)
+
+	var out LLMSQLOutput
+	var llmResult llmCallResult
+	var vote *VoteSummary
+	var prefetched *QueryResult
+	var usage llmUsage
+	stageStart = time.Now()
+	llmCtx, cancelLLM := stageContext(ctx, budgetShareLLM)
+	defer cancelLLM()
+	if n := candidateCount(opts.Candidates); n > 1 {
+		log.Printf("🗳️ Membuat %d kandidat SQL untuk voting...", n)
+		candidates := generateSQLCandidates(llmCtx, finalPrompt, n)
+		for _, c := range candidates {
+			usage.PromptTokens += c.LLM.Usage.PromptTokens
+			usage.CompletionTokens += c.LLM.Usage.CompletionTokens
+			usage.TotalTokens += c.LLM.Usage.TotalTokens
 		}
-
-		req, err := http.NewRequest("POST", AppConfig.GroqAPIURL, bytes.NewBuffer(jsonBody))
+		outcome, err := voteSQLCandidates(candidates)
+		opts.Timings.observe("llm", stageStart)
 		if err != nil {
 			return AISqlResponse{}, err
 		}
-		req.Header.Set("Authorization", "Bearer "+AppConfig.GroqAPIKey)
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{Timeout: AppConfig.GroqTimeout}
-		resp, err := client.Do(req)
-		if err != nil {
-			return AISqlResponse{}, fmt.Errorf("gagal memanggil Groq (dan Ollama juga gagal): %w", err)
+		out, llmResult = outcome.Winner.Output, outcome.Winner.LLM
+		vote = &outcome.Summary
+		if outcome.Clarification == nil && !outcome.Winner.Truncated {
+			result := outcome.Winner.Result
+			prefetched = &result
 		}
-		defer resp.Body.Close()
-
-		respBodyBytes, err := io.ReadAll(resp.Body)
+	} else {
+		out, llmResult, err = generateLLMSQLOutput(llmCtx, finalPrompt, 0, "")
+		opts.Timings.observe("llm", stageStart)
 		if err != nil {
 			return AISqlResponse{}, err
 		}
-		if resp.StatusCode != http.StatusOK {
-			return AISqlResponse{}, fmt.Errorf("Groq merespon dengan error: %s", string(respBodyBytes))
-		}
-
-		var groqResp GroqResponse
-		if err := json.Unmarshal(respBodyBytes, &groqResp); err != nil {
-			return AISqlResponse{}, err
-		}
-		if len(groqResp.Choices) == 0 {
-			return AISqlResponse{}, errors.New("AI Groq tidak memberikan balasan")
-		}
-
-		rawContent = groqResp.Choices[0].Message.Content
+		usage = llmResult.Usage
 	}
-	log.Printf("🤖 RAW AI Response:\n%s\n", rawContent)
-	re := regexp.MustCompile("(?s)```sql(.*?)```")
-	match := re.FindStringSubmatch(rawContent)
 
-	var sqlQuery string
-	if len(match) > 1 {
-		sqlQuery = strings.TrimSpace(match[1])
-	} else {
-		log.Println("⚠️ AI tidak menggunakan format markdown SQL, mencoba membersihkan manual...")
-		sqlQuery = strings.TrimSpace(rawContent)
-		if idx := strings.Index(strings.ToLower(sqlQuery), "select"); idx != -1 {
-			sqlQuery = sqlQuery[idx:]
+	if out.Clarification != nil {
+		c := out.Clarification
+		log.Printf("❓ AI meminta klarifikasi: %s", c.Reason)
+		suggestions := c.Rephrasings
+		if len(suggestions) == 0 {
+			suggestions = NearestExamplePrompts(ctx, userPrompt, promptVector, AppConfig.SuggestionCount)
 		}
+		return AISqlResponse{
+			Vector:        promptVector,
+			PromptAsli:    userPrompt,
+			IsAmbiguous:   true,
+			Suggestions:   suggestions,
+			Clarification: c,
+			Reasoning:     out.Reasoning,
+			Provider:      llmResult.Provider,
+			Model:         llmResult.Model,
+			PromptVersion: promptTmpl.Version,
+			Vote:          vote,
+			Source:        source,
+			RAGScore:      topDense,
+			CacheScore:    topCacheScore,
+			RAGExamples:   ragExamples,
+			Usage:         usage,
+			Degraded:      degraded,
+		}, nil
 	}
 
-	log.Println("SQL dari AI (Extracted):", sqlQuery)
-	log.Println("SQL dari AI (Dynamic RAG):", sqlQuery)
-	sqlQuery = sanitizeSQL(sqlQuery)
-	if sqlQuery == "" {
-		return AISqlResponse{}, errors.New("SQL tidak aman atau tidak valid")
-	}
+	log.Println("SQL dari AI (Dynamic RAG):", out.SQL)
 
 	return AISqlResponse{
-		SQL:        sqlQuery,
-		Vector:     promptVector,
-		PromptAsli: userPrompt,
-		IsCached:   false,
+		SQL:           out.SQL,
+		Vector:        promptVector,
+		PromptAsli:    userPrompt,
+		IsCached:      false,
+		Reasoning:     out.Reasoning,
+		TablesUsed:    out.TablesUsed,
+		LLMConfidence: *out.Confidence,
+		Provider:      llmResult.Provider,
+		Model:         llmResult.Model,
+		PromptVersion: promptTmpl.Version,
+		Vote:          vote,
+		Result:        prefetched,
+		Source:        source,
+		RAGScore:      topDense,
+		CacheScore:    topCacheScore,
+		RAGExamples:   ragExamples,
+		Usage:         usage,
+		Degraded:      degraded,
 	}, nil
 }
 
@@ -415,47 +500,6 @@ func getQdrantBaseURL() string {
 	return base
 }
 
-func httpDoJSON(ctx context.Context, method, url string, body any) (*http.Response, []byte, error) {
-	var reqBody io.Reader
-	if body != nil {
-		b, err := json.Marshal(body)
-		if err != nil {
-			return nil, nil, fmt.Errorf("gagal marshal JSON: %w", err)
-		}
-		reqBody = bytes.NewReader(b)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return nil, nil, fmt.Errorf("gagal buat request: %w", err)
-	}
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	timeout := 60 * time.Second
-	if AppConfig != nil {
-		timeout = AppConfig.QdrantTimeout
-	}
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, nil, fmt.Errorf("gagal call %s %s: %w", method, url, err)
-	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			resp.Body.Close()
-		}
-	}()
-
-	respBody, readErr := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	if readErr != nil {
-		return resp, nil, fmt.Errorf("gagal baca response body: %w", readErr)
-	}
-	return resp, respBody, nil
-}
-
 func qdrantCreateCollection(ctx context.Context, baseURL, name string, size int, distance string) error {
 	url := fmt.Sprintf("%s/collections/%s", baseURL, name)
 	req := qdrantCreateCollectionReq{
@@ -464,7 +508,7 @@ func qdrantCreateCollection(ctx context.Context, baseURL, name string, size int,
 			Distance: distance,
 		},
 	}
-	resp, body, err := httpDoJSON(ctx, http.MethodPut, url, req)
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPut, url, req, nil)
 	if err != nil {
 		return err
 	}
@@ -486,7 +530,7 @@ func qdrantCreateCollection(ctx context.Context, baseURL, name string, size int,
 func qdrantUpsertPoints(ctx context.Context, baseURL, name string, points []qdrantPoint) error {
 	url := fmt.Sprintf("%s/collections/%s/points?wait=true", baseURL, name)
 	req := qdrantUpsertPointsReq{Points: points}
-	resp, body, err := httpDoJSON(ctx, http.MethodPut, url, req)
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPut, url, req, nil)
 	if err != nil {
 		return err
 	}
@@ -526,7 +570,7 @@ func qdrantSearchPoints(ctx context.Context, baseURL, name string, req qdrantSea
 	url := fmt.Sprintf("%s/collections/%s/points/search", baseURL, name)
 	var respData qdrantSearchResp
 
-	resp, body, err := httpDoJSON(ctx, http.MethodPost, url, req)
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPost, url, req, nil, withIdempotent(true))
 	if err != nil {
 		return respData, err
 	}
@@ -540,13 +584,18 @@ func qdrantSearchPoints(ctx context.Context, baseURL, name string, req qdrantSea
 	return respData, nil
 }
 
-func SaveToCache(promptAsli string, promptVector []float32, sqlQuery string) {
+func SaveToCache(promptAsli string, promptVector []float32, sqlQuery string, promptVersion string) {
 	go func() {
 		if AppConfig == nil {
 			log.Println("PERINGATAN: Konfigurasi belum dimuat, tidak bisa menyimpan ke cache")
 			return
 		}
 
+		if VectorServiceDegraded() {
+			log.Println("Mode degraded: hasil tidak disimpan ke semantic cache.")
+			return
+		}
+
 		ctx := context.Background()
 
 		log.Println("Menyimpan hasil (yang sudah tervalidasi) ke Semantic Cache (REST)...")
@@ -555,8 +604,10 @@ func SaveToCache(promptAsli string, promptVector []float32, sqlQuery string) {
 			ID:     uuid.NewString(),
 			Vector: promptVector,
 			Payload: map[string]interface{}{
-				"prompt_asli": promptAsli,
-				"sql_query":   sqlQuery,
+				"prompt_asli":    promptAsli,
+				"sql_query":      sqlQuery,
+				"schema_version": CurrentSchemaVersion(),
+				"prompt_version": promptVersion,
 			},
 		}
 
@@ -569,6 +620,14 @@ func SaveToCache(promptAsli string, promptVector []float32, sqlQuery string) {
 	}()
 }
 
+// pointIDString mengubah ID point Qdrant (UUID atau angka) menjadi string.
+func pointIDString(id *pb.PointId) string {
+	if id.GetUuid() != "" {
+		return id.GetUuid()
+	}
+	return fmt.Sprintf("%d", id.GetNum())
+}
+
 func convertQdrantValue(value *pb.Value) interface{} {
 	switch k := value.Kind.(type) {
 	case *pb.Value_NullValue:
@@ -598,10 +657,8 @@ func convertQdrantValue(value *pb.Value) interface{} {
 	}
 }
 
-func GetAllQdrantPoints(collectionName string, limit uint32) ([]QdrantDataResponse, error) {
-	ctx := context.Background()
-
-	scrollResp, err := qdrantClient.Scroll(ctx, &pb.ScrollPoints{
+func GetAllQdrantPoints(ctx context.Context, collectionName string, limit uint32) ([]QdrantDataResponse, error) {
+	scrollResp, err := qdrantScroll(ctx, &pb.ScrollPoints{
 		CollectionName: collectionName,
 		Limit:          &limit,
 		WithPayload:    pb.NewWithPayload(true),
@@ -615,12 +672,7 @@ func GetAllQdrantPoints(collectionName string, limit uint32) ([]QdrantDataRespon
 
 	var results []QdrantDataResponse
 	for _, item := range scrollResp {
-		var idStr string
-		if item.Id.GetUuid() != "" {
-			idStr = item.Id.GetUuid()
-		} else {
-			idStr = fmt.Sprintf("%d", item.Id.GetNum())
-		}
+		idStr := pointIDString(item.Id)
 
 		cleanPayload := make(map[string]interface{})
 		for key, value := range item.Payload {
@@ -636,8 +688,8 @@ func GetAllQdrantPoints(collectionName string, limit uint32) ([]QdrantDataRespon
 	return results, nil
 }
 
-func UpdateQdrantPoint(collectionName string, id string, prompt string, sqlQuery string) error {
-	vector, err := GenerateEmbedding(prompt)
+func UpdateQdrantPoint(ctx context.Context, collectionName string, id string, prompt string, sqlQuery string) error {
+	vector, err := GenerateEmbedding(ctx, prompt)
 	if err != nil {
 		return fmt.Errorf("gagal generate embedding saat update: %w", err)
 	}
@@ -651,7 +703,6 @@ func UpdateQdrantPoint(collectionName string, id string, prompt string, sqlQuery
 		},
 	}
 
-	ctx := context.Background()
 	err = qdrantUpsertPoints(ctx, AppConfig.QdrantURL, collectionName, []qdrantPoint{point})
 	if err != nil {
 		return fmt.Errorf("gagal update ke qdrant: %w", err)
@@ -661,13 +712,17 @@ func UpdateQdrantPoint(collectionName string, id string, prompt string, sqlQuery
 	return nil
 }
 
-func GenerateEmbedding(text string) ([]float32, error) {
+func GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	if geminiEmbedder == nil {
 		return nil, fmt.Errorf("service embedding belum diinisialisasi")
 	}
 
-	ctx := context.Background()
-	res, err := geminiEmbedder.EmbedContent(ctx, genai.Text(text))
+	var res *genai.EmbedContentResponse
+	err := resilientCall(ctx, depGemini, func(ctx context.Context) callOutcome {
+		var err error
+		res, err = geminiEmbedder.EmbedContent(ctx, genai.Text(text))
+		return googleAPIOutcome(err)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -675,8 +730,8 @@ func GenerateEmbedding(text string) ([]float32, error) {
 	return res.Embedding.Values, nil
 }
 
-func ManualInjectCache(promptAsli string, sqlQuery string) error {
-	vector, err := GenerateEmbedding(promptAsli)
+func ManualInjectCache(ctx context.Context, promptAsli string, sqlQuery string) error {
+	vector, err := GenerateEmbedding(ctx, promptAsli)
 	if err != nil {
 		return fmt.Errorf("gagal membuat embedding: %w", err)
 	}
@@ -690,7 +745,6 @@ func ManualInjectCache(promptAsli string, sqlQuery string) error {
 		},
 	}
 
-	ctx := context.Background()
 	err = qdrantUpsertPoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection, []qdrantPoint{point})
 	if err != nil {
 		return fmt.Errorf("gagal upsert ke qdrant: %w", err)
@@ -712,7 +766,7 @@ func DeleteQdrantPoint(ctx context.Context, collectionName string, pointID strin
 		Points: []string{pointID},
 	}
 
-	resp, body, err := httpDoJSON(ctx, http.MethodPost, url, reqPayload)
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPost, url, reqPayload, nil, withIdempotent(true))
 	if err != nil {
 		return fmt.Errorf("gagal request ke qdrant: %w", err)
 	}
@@ -724,10 +778,52 @@ func DeleteQdrantPoint(ctx context.Context, collectionName string, pointID strin
 	log.Printf("Berhasil menghapus Point ID '%s' dari collection '%s'", pointID, collectionName)
 	return nil
 }
+
+func qdrantDeletePoints(ctx context.Context, baseURL, name string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	url := fmt.Sprintf("%s/collections/%s/points/delete?wait=true", baseURL, name)
+
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPost, url, qdrantDeletePointsReq{Points: ids}, nil, withIdempotent(true))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("qdrant delete points status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// InvalidateSimilarCache menghapus item semantic cache yang akan menjadi CACHE HIT untuk
+// promptVector (skor >= CacheSimilarityThreshold). Mengembalikan jumlah item yang dihapus.
+func InvalidateSimilarCache(ctx context.Context, promptVector []float32) (int, error) {
+	resp, err := qdrantSearchPoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection, qdrantSearchReq{
+		Vector:         promptVector,
+		Limit:          50,
+		WithPayload:    true,
+		ScoreThreshold: AppConfig.CacheSimilarityThreshold,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("gagal mencari cache serupa: %w", err)
+	}
+
+	var ids []string
+	for _, hit := range resp.Result {
+		ids = append(ids, fmt.Sprint(hit.ID))
+		log.Printf("Invalidasi cache: '%v' (Skor: %f)", hit.Payload["prompt_asli"], hit.Score)
+	}
+	if err := qdrantDeletePoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection, ids); err != nil {
+		return 0, fmt.Errorf("gagal menghapus cache serupa: %w", err)
+	}
+	return len(ids), nil
+}
+
 func qdrantDeleteCollection(ctx context.Context, baseURL, name string) error {
 	url := fmt.Sprintf("%s/collections/%s", baseURL, name)
 
-	resp, body, err := httpDoJSON(ctx, http.MethodDelete, url, nil)
+	// Tidak diulang: penghapusan yang sudah diproses tapi responsnya hilang akan dilaporkan 404
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodDelete, url, nil, nil, withIdempotent(false))
 	if err != nil {
 		return err
 	}
@@ -739,3 +835,202 @@ func qdrantDeleteCollection(ctx context.Context, baseURL, name string) error {
 
 	return fmt.Errorf("gagal hapus collection status %d: %s", resp.StatusCode, string(body))
 }
+
+func qdrantCollectionExists(ctx context.Context, baseURL, name string) (bool, error) {
+	url := fmt.Sprintf("%s/collections/%s", baseURL, name)
+
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("cek collection status %d: %s", resp.StatusCode, string(body))
+}
+
+// qdrantCollectionInfo adalah ringkasan GET /collections/{name} untuk health check.
+type qdrantCollectionInfo struct {
+	Exists      bool
+	VectorSize  int
+	PointsCount int64
+}
+
+// qdrantGetCollectionInfo membaca dimensi vektor dan jumlah point sebuah collection (atau alias).
+// Untuk named vectors, dimensi diambil dari vektor pertama. Dipakai health check, sehingga
+// hanya satu percobaan tanpa retry & circuit breaker.
+func qdrantGetCollectionInfo(ctx context.Context, baseURL, name string) (qdrantCollectionInfo, error) {
+	url := fmt.Sprintf("%s/collections/%s", baseURL, name)
+
+	var info qdrantCollectionInfo
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodGet, url, nil, nil, withProbe())
+	if err != nil {
+		return info, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return info, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("info collection status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var respData struct {
+		Result struct {
+			PointsCount *int64 `json:"points_count"`
+			Config      struct {
+				Params struct {
+					Vectors json.RawMessage `json:"vectors"`
+				} `json:"params"`
+			} `json:"config"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &respData); err != nil {
+		return info, fmt.Errorf("gagal unmarshal info collection: %w", err)
+	}
+	info.Exists = true
+	if respData.Result.PointsCount != nil {
+		info.PointsCount = *respData.Result.PointsCount
+	}
+
+	var single qdrantVectors
+	if err := json.Unmarshal(respData.Result.Config.Params.Vectors, &single); err == nil && single.Size > 0 {
+		info.VectorSize = single.Size
+		return info, nil
+	}
+	var named map[string]qdrantVectors
+	if err := json.Unmarshal(respData.Result.Config.Params.Vectors, &named); err == nil {
+		for _, v := range named {
+			info.VectorSize = v.Size
+			break
+		}
+	}
+	return info, nil
+}
+
+type qdrantAliasDescription struct {
+	AliasName      string `json:"alias_name"`
+	CollectionName string `json:"collection_name"`
+}
+
+type qdrantAliasesResp struct {
+	Result struct {
+		Aliases []qdrantAliasDescription `json:"aliases"`
+	} `json:"result"`
+}
+
+// qdrantResolveAlias mengembalikan nama collection fisik di balik alias, atau "" jika alias belum ada.
+func qdrantResolveAlias(ctx context.Context, baseURL, alias string) (string, error) {
+	url := fmt.Sprintf("%s/collections/aliases", baseURL)
+
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("list aliases status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var respData qdrantAliasesResp
+	if err := json.Unmarshal(body, &respData); err != nil {
+		return "", fmt.Errorf("gagal unmarshal aliases response: %w", err)
+	}
+	for _, a := range respData.Result.Aliases {
+		if a.AliasName == alias {
+			return a.CollectionName, nil
+		}
+	}
+	return "", nil
+}
+
+// qdrantSwapAlias memindahkan alias ke collection baru secara atomik (delete + create dalam satu request).
+func qdrantSwapAlias(ctx context.Context, baseURL, alias, collection string, aliasExists bool) error {
+	url := fmt.Sprintf("%s/collections/aliases", baseURL)
+
+	var actions []map[string]any
+	if aliasExists {
+		actions = append(actions, map[string]any{
+			"delete_alias": map[string]any{"alias_name": alias},
+		})
+	}
+	actions = append(actions, map[string]any{
+		"create_alias": map[string]any{"collection_name": collection, "alias_name": alias},
+	})
+
+	// Tidak diulang: delete_alias yang sudah diterapkan membuat percobaan ulang gagal di tengah swap
+	resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPost, url, map[string]any{"actions": actions}, nil, withIdempotent(false))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("swap alias status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+type qdrantScrollReq struct {
+	Limit       int  `json:"limit"`
+	Offset      any  `json:"offset,omitempty"`
+	WithPayload bool `json:"with_payload"`
+	WithVector  bool `json:"with_vector"`
+}
+
+type qdrantScrollResp struct {
+	Result struct {
+		Points []struct {
+			ID      any                    `json:"id"`
+			Vector  []float32              `json:"vector"`
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"points"`
+		NextPageOffset any `json:"next_page_offset"`
+	} `json:"result"`
+}
+
+type qdrantScrolledPoint struct {
+	ID      string
+	Vector  []float32
+	Payload map[string]interface{}
+}
+
+// qdrantScrollAllPoints membaca seluruh point (beserta vektor) dari sebuah collection via REST.
+func qdrantScrollAllPoints(ctx context.Context, baseURL, name string) ([]qdrantScrolledPoint, error) {
+	url := fmt.Sprintf("%s/collections/%s/points/scroll", baseURL, name)
+
+	var points []qdrantScrolledPoint
+	var offset any
+	for {
+		resp, body, err := httpClient(depQdrant).DoJSON(ctx, http.MethodPost, url, qdrantScrollReq{
+			Limit:       256,
+			Offset:      offset,
+			WithPayload: true,
+			WithVector:  true,
+		}, nil, withIdempotent(true))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("scroll points status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var page qdrantScrollResp
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("gagal unmarshal scroll response: %w", err)
+		}
+
+		for _, p := range page.Result.Points {
+			points = append(points, qdrantScrolledPoint{
+				ID:      fmt.Sprintf("%v", p.ID),
+				Vector:  p.Vector,
+				Payload: p.Payload,
+			})
+		}
+
+		if page.Result.NextPageOffset == nil {
+			break
+		}
+		offset = page.Result.NextPageOffset
+	}
+	return points, nil
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type cliCommand struct {
+	Usage string
+	Run   func(args []string) error
+}
+
+var cliCommands map[string]cliCommand
+
+func init() {
+	cliCommands = map[string]cliCommand{
+		"serve":    {Usage: "serve                                  Jalankan HTTP server (default)", Run: cmdServe},
+		"train":    {Usage: "train [--incremental]                  Sinkronkan DDL & contoh SQL ke Qdrant", Run: cmdTrain},
+		"cache":    {Usage: "cache list|purge|export|import         Kelola semantic cache", Run: cmdCache},
+		"examples": {Usage: "examples add|list|seed                 Kelola tabel rag_sql_examples", Run: cmdExamples},
+		"schema":   {Usage: "schema dump                            Cetak DDL & data referensi yang dikirim ke LLM", Run: cmdSchema},
+		"ask":      {Usage: "ask [--confirm] \"prompt\"               Jalankan pipeline lokal, cetak SQL + hasil", Run: cmdAsk},
+		"eval":     {Usage: "eval [--file F] [--format F]           Ukur akurasi text-to-SQL terhadap golden set", Run: cmdEval},
+		"migrate":  {Usage: "migrate up|down [--steps N]|status     Kelola migrasi tabel metadata service", Run: cmdMigrate},
+	}
+}
+
+func printCLIUsage(w io.Writer) {
+	fmt.Fprintln(w, "Penggunaan: go-bank-api <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", cliCommands[name].Usage)
+	}
+}
+
+// runCLI menjalankan subcommand dan mengembalikan exit code. Tanpa argumen, server dijalankan.
+func runCLI(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printCLIUsage(os.Stdout)
+		return 0
+	}
+
+	cmd, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Command tidak dikenal: %s\n\n", name)
+		printCLIUsage(os.Stderr)
+		return 2
+	}
+
+	if err := cmd.Run(args); err != nil {
+		log.Printf("Fatal Error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func cmdServe(args []string) error {
+	return runServe()
+}
+
+func cmdTrain(args []string) error {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	incremental := fs.Bool("incremental", false, "embed hanya item baru/berubah, pakai ulang vektor lainnya")
+	fs.Parse(args)
+
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+
+	result, err := RunTraining(context.Background(), TrainOptions{Incremental: *incremental})
+	if err != nil {
+		return err
+	}
+	return printJSON(os.Stdout, result)
+}
+
+type cacheExportItem struct {
+	ID         string `json:"id,omitempty"`
+	PromptAsli string `json:"prompt_asli"`
+	SQLQuery   string `json:"sql_query"`
+}
+
+func cmdCache(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("penggunaan: cache list|purge|export|import")
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("cache "+sub, flag.ExitOnError)
+	file := fs.String("file", "", "file JSON untuk export/import (default stdout/stdin)")
+	yes := fs.Bool("yes", false, "konfirmasi purge seluruh cache")
+	fs.Parse(args)
+
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch sub {
+	case "list", "export":
+		points, err := qdrantScrollAllPoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection)
+		if err != nil {
+			return err
+		}
+		items := make([]cacheExportItem, 0, len(points))
+		for _, p := range points {
+			prompt, _ := p.Payload["prompt_asli"].(string)
+			sqlQuery, _ := p.Payload["sql_query"].(string)
+			items = append(items, cacheExportItem{ID: p.ID, PromptAsli: prompt, SQLQuery: sqlQuery})
+		}
+
+		if sub == "list" {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tPROMPT\tSQL")
+			for _, it := range items {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", it.ID, it.PromptAsli, oneLine(it.SQLQuery))
+			}
+			return tw.Flush()
+		}
+
+		out := io.Writer(os.Stdout)
+		if *file != "" {
+			f, err := os.Create(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		return printJSON(out, items)
+
+	case "purge":
+		if !*yes {
+			return fmt.Errorf("purge menghapus seluruh collection '%s'; ulangi dengan --yes", AppConfig.QdrantCacheCollection)
+		}
+		if err := qdrantDeleteCollection(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection); err != nil {
+			return err
+		}
+		return qdrantCreateCollection(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection,
+			AppConfig.EmbeddingVectorSize, AppConfig.QdrantDistanceMetric)
+
+	case "import":
+		in := io.Reader(os.Stdin)
+		if *file != "" {
+			f, err := os.Open(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		var items []cacheExportItem
+		if err := json.NewDecoder(in).Decode(&items); err != nil {
+			return fmt.Errorf("file import tidak valid: %w", err)
+		}
+
+		imported := 0
+		for _, it := range items {
+			if err := validateReadOnlySQL(it.SQLQuery); err != nil {
+				log.Printf("Skip '%s': %v", it.PromptAsli, err)
+				continue
+			}
+			if err := ManualInjectCache(ctx, it.PromptAsli, it.SQLQuery); err != nil {
+				log.Printf("Skip '%s': %v", it.PromptAsli, err)
+				continue
+			}
+			imported++
+		}
+		fmt.Printf("%d dari %d item cache berhasil diimpor.\n", imported, len(items))
+		return nil
+	}
+
+	return fmt.Errorf("subcommand cache tidak dikenal: %s", sub)
+}
+
+func cmdExamples(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("penggunaan: examples add|list|seed")
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("examples "+sub, flag.ExitOnError)
+	prompt := fs.String("prompt", "", "pertanyaan bahasa natural")
+	sqlQuery := fs.String("sql", "", "SQL yang benar untuk pertanyaan tersebut")
+	dir := fs.String("dir", "", "direktori file seed YAML/JSON (default SEED_EXAMPLES_DIR)")
+	fs.Parse(args)
+
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+
+	switch sub {
+	case "list":
+		examples, err := GetDynamicSqlExamples()
+		if err != nil {
+			return err
+		}
+		for _, ex := range examples {
+			fmt.Println(ex.FullContent)
+			fmt.Println("---")
+		}
+		return nil
+
+	case "add":
+		if strings.TrimSpace(*prompt) == "" || strings.TrimSpace(*sqlQuery) == "" {
+			return fmt.Errorf("--prompt dan --sql wajib diisi")
+		}
+		if err := validateReadOnlySQL(*sqlQuery); err != nil {
+			return err
+		}
+		return AddSqlExample(strings.TrimSpace(*prompt), strings.TrimSpace(*sqlQuery))
+
+	case "seed":
+		seedDir := AppConfig.SeedExamplesDir
+		if *dir != "" {
+			seedDir = *dir
+		}
+		result, err := SeedSqlExamples(context.Background(), seedDir)
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, result)
+	}
+
+	return fmt.Errorf("subcommand examples tidak dikenal: %s", sub)
+}
+
+func cmdSchema(args []string) error {
+	if len(args) == 0 || args[0] != "dump" {
+		return fmt.Errorf("penggunaan: schema dump")
+	}
+
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+
+	snap, err := RefreshSchemaSnapshot(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("-- Schema snapshot versi %s\n\n", snap.Version)
+	fmt.Println(strings.Join(snap.DDLs, "\n\n"))
+	fmt.Println()
+	fmt.Println(snap.ReferenceData)
+	fmt.Println(renderBusinessDictionary(snap.Dictionary))
+	return nil
+}
+
+func cmdAsk(args []string) error {
+	fs := flag.NewFlagSet("ask", flag.ExitOnError)
+	confirm := fs.Bool("confirm", false, "jalankan query walau confidence di bawah CONFIDENCE_THRESHOLD")
+	promptVersion := fs.String("prompt-version", "", "paksa versi template prompt (default/rollout jika kosong)")
+	candidates := fs.Int("candidates", 0, "jumlah kandidat SQL untuk voting (0 = SQL_CANDIDATES)")
+	fs.Parse(args)
+
+	prompt := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if prompt == "" {
+		return fmt.Errorf("penggunaan: ask [--confirm] \"prompt\"")
+	}
+
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if AppConfig.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, AppConfig.RequestTimeout)
+		defer cancel()
+	}
+
+	// Pipeline yang sama dengan /api/query; CLI dijalankan operator, jadi semua info debug ditampilkan
+	include := make(map[string]bool, len(allDebugIncludes))
+	for _, it := range allDebugIncludes {
+		include[it] = true
+	}
+	resp, err := RunDynamicQuery(ctx, PromptRequest{
+		Prompt:        prompt,
+		PromptVersion: *promptVersion,
+		Candidates:    *candidates,
+		Confirm:       *confirm,
+	}, include)
+	if err != nil {
+		return err
+	}
+
+	switch resp.Status {
+	case queryStatusAmbiguous:
+		fmt.Println(resp.Message)
+		for _, q := range resp.Questions {
+			fmt.Printf("  ? %s\n", q)
+		}
+		if len(resp.Suggestions) > 0 {
+			fmt.Println("Saran:")
+			for _, s := range resp.Suggestions {
+				fmt.Printf("  - %s\n", s)
+			}
+		}
+		return nil
+	}
+
+	if d := resp.Debug; d != nil {
+		cached := d.IsCached != nil && *d.IsCached
+		fmt.Printf("SQL (cached=%t, prompt=%s):\n%s\n\n", cached, d.PromptVersion, d.SQL)
+	}
+	if c := resp.ConfidenceDetail; c != nil {
+		fmt.Printf("Confidence: %.3f (source=%s)\n", c.Score, c.Source)
+		for _, w := range c.Warnings {
+			fmt.Printf("  ! %s\n", w)
+		}
+		fmt.Println()
+	}
+	if v := resp.Voting; v != nil {
+		fmt.Printf("Voting: %d/%d kandidat sepakat (%d cluster), confidence %.2f\n\n", v.Agreeing, v.Candidates, v.Clusters, v.Confidence)
+	}
+
+	if resp.Status == queryStatusConfirmationRequired {
+		fmt.Printf("Tingkat keyakinan jawaban rendah; query tidak dijalankan.\n")
+		if resp.Interpretation != "" {
+			fmt.Printf("Interpretasi: %s\n", resp.Interpretation)
+		}
+		for _, s := range resp.Suggestions {
+			fmt.Printf("  - %s\n", s)
+		}
+		fmt.Println("Ulangi dengan --confirm untuk tetap menjalankan query.")
+		return nil
+	}
+
+	data, _ := resp.Data.(QueryResult)
+	return printQueryResult(os.Stdout, data)
+}
+
+func cmdEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	file := fs.String("file", "", "golden set JSON/YAML (default EVAL_GOLDEN_FILE)")
+	format := fs.String("format", "markdown", "format laporan: json atau markdown")
+	outFile := fs.String("out", "", "tulis laporan ke file (default stdout)")
+	limit := fs.Int("limit", 0, "jalankan hanya N case pertama (0 = semua)")
+	promptVersion := fs.String("prompt-version", "", "paksa versi template prompt (default/rollout jika kosong)")
+	candidates := fs.Int("candidates", 0, "jumlah kandidat SQL untuk voting (0 = SQL_CANDIDATES)")
+	useCache := fs.Bool("use-cache", false, "pakai semantic cache (default dilewati agar yang diukur adalah model)")
+	fs.Parse(args)
+
+	if *format != "json" && *format != "markdown" {
+		return fmt.Errorf("--format harus json atau markdown")
+	}
+
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+
+	path := AppConfig.EvalGoldenFile
+	if *file != "" {
+		path = *file
+	}
+	cases, err := LoadEvalCases(path)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && *limit < len(cases) {
+		cases = cases[:*limit]
+	}
+
+	report := RunEval(context.Background(), cases, SQLGenOptions{
+		PromptVersion: *promptVersion,
+		Candidates:    *candidates,
+		SkipCache:     !*useCache,
+	})
+
+	out := io.Writer(os.Stdout)
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == "json" {
+		return printJSON(out, report)
+	}
+	return WriteEvalMarkdown(out, report)
+}
+
+func cmdMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("penggunaan: migrate up|down|status")
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("migrate "+sub, flag.ExitOnError)
+	steps := fs.Int("steps", 1, "jumlah migrasi yang dibatalkan (down)")
+	fs.Parse(args)
+
+	// Migrasi dijalankan eksplisit di sini, bukan lewat MIGRATE_ON_START
+	if err := bootstrap(bootstrapOptions{autoMigrate: false}); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch sub {
+	case "up":
+		n, err := MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrasi diterapkan.\n", n)
+		return nil
+
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("--steps minimal 1")
+		}
+		n, err := MigrateDown(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migrasi dibatalkan.\n", n)
+		return nil
+
+	case "status":
+		statuses, err := MigrationStatuses(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			appliedAt := "-"
+			if st.AppliedAt != nil {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("subcommand migrate tidak dikenal: %s", sub)
+}
+
+func printQueryResult(w io.Writer, result QueryResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				cells[i] = string(b)
+			} else if v == nil {
+				cells[i] = "NULL"
+			} else {
+				cells[i] = fmt.Sprintf("%v", v)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "(%d baris)\n", len(result.Rows))
+	return nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
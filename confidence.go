@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Asal SQL yang dikembalikan pipeline.
+const (
+	SQLSourceCache    = "cache"
+	SQLSourceRAG      = "rag"
+	SQLSourceZeroShot = "zero_shot"
+)
+
+// Bobot komponen confidence untuk SQL hasil LLM; komponen yang tidak tersedia
+// (mis. voting tidak aktif) dikeluarkan dan bobot sisanya dinormalisasi.
+const (
+	confidenceWeightRAG  = 0.3
+	confidenceWeightLLM  = 0.4
+	confidenceWeightVote = 0.3
+	// Pengurangan skor untuk setiap peringatan validator
+	confidenceWarningPenalty = 0.1
+)
+
+var (
+	sqlTableRefRe = regexp.MustCompile(`(?i)\b(?:from|join)\s+([a-z_][a-z0-9_."]*)`)
+	sqlCTENameRe  = regexp.MustCompile(`(?i)\b([a-z_][a-z0-9_]*)\s+as\s*\(`)
+	sqlSelectStar = regexp.MustCompile(`(?i)\bselect\s+(distinct\s+)?\*`)
+)
+
+// ConfidenceBreakdown adalah skor keyakinan gabungan beserta komponennya.
+type ConfidenceBreakdown struct {
+	Score          float64  `json:"score"`
+	Source         string   `json:"source"`
+	CacheScore     *float64 `json:"cache_score,omitempty"`
+	RAGScore       *float64 `json:"rag_score,omitempty"`
+	LLMConfidence  *float64 `json:"llm_confidence,omitempty"`
+	VoteConfidence *float64 `json:"vote_confidence,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// IsLowConfidence bernilai true jika skor di bawah CONFIDENCE_THRESHOLD (0 = nonaktif).
+func (c *ConfidenceBreakdown) IsLowConfidence() bool {
+	return c != nil && AppConfig != nil && AppConfig.ConfidenceThreshold > 0 && c.Score < AppConfig.ConfidenceThreshold
+}
+
+// ScoreConfidence menggabungkan skor cache, skor RAG teratas, self-assessment LLM, hasil voting
+// dan peringatan validator menjadi satu skor 0..1.
+func ScoreConfidence(aiResp AISqlResponse, snap *SchemaSnapshot) *ConfidenceBreakdown {
+	c := &ConfidenceBreakdown{Source: aiResp.Source}
+	c.Warnings = sqlValidatorWarnings(aiResp, snap)
+
+	var score float64
+	if aiResp.Source == SQLSourceCache {
+		cacheScore := float64(aiResp.CacheScore)
+		c.CacheScore = &cacheScore
+		score = cacheScore
+	} else {
+		var weighted, totalWeight float64
+		add := func(value float64, weight float64) *float64 {
+			weighted += value * weight
+			totalWeight += weight
+			return &value
+		}
+		// Tanpa Qdrant tidak ada skor dense; komponen RAG dikeluarkan dan diganti peringatan
+		if aiResp.Degraded {
+			c.Warnings = append(c.Warnings, "mode degraded: Qdrant tidak tersedia, contoh SQL dari indeks lokal")
+		} else {
+			c.RAGScore = add(float64(aiResp.RAGScore), confidenceWeightRAG)
+		}
+		c.LLMConfidence = add(aiResp.LLMConfidence, confidenceWeightLLM)
+		if aiResp.Vote != nil {
+			c.VoteConfidence = add(aiResp.Vote.Confidence, confidenceWeightVote)
+		}
+		if totalWeight > 0 {
+			score = weighted / totalWeight
+		}
+	}
+
+	score -= confidenceWarningPenalty * float64(len(c.Warnings))
+	c.Score = math.Round(math.Max(0, math.Min(1, score))*1000) / 1000
+	return c
+}
+
+// sqlValidatorWarnings mencari tanda-tanda SQL yang meragukan tanpa mengeksekusinya.
+func sqlValidatorWarnings(aiResp AISqlResponse, snap *SchemaSnapshot) []string {
+	var warnings []string
+
+	known := make(map[string]bool)
+	// Kolom juga dicatat agar "EXTRACT(YEAR FROM kolom)" tidak dianggap referensi tabel
+	columns := make(map[string]bool)
+	if snap != nil {
+		for _, t := range snap.Tables {
+			known[strings.ToLower(t.Name)] = true
+			for _, col := range t.Columns {
+				columns[strings.ToLower(col.Name)] = true
+			}
+		}
+	}
+	baseName := func(ident string) string {
+		ident = strings.ToLower(strings.ReplaceAll(ident, `"`, ""))
+		if idx := strings.LastIndex(ident, "."); idx != -1 {
+			ident = ident[idx+1:]
+		}
+		return ident
+	}
+
+	if len(known) > 0 {
+		ctes := make(map[string]bool)
+		for _, m := range sqlCTENameRe.FindAllStringSubmatch(aiResp.SQL, -1) {
+			ctes[strings.ToLower(m[1])] = true
+		}
+		seen := make(map[string]bool)
+		for _, m := range sqlTableRefRe.FindAllStringSubmatch(aiResp.SQL, -1) {
+			name := baseName(m[1])
+			if known[name] || ctes[name] || columns[name] || seen[name] {
+				continue
+			}
+			seen[name] = true
+			warnings = append(warnings, fmt.Sprintf("tabel '%s' tidak ada di skema", name))
+		}
+		for _, t := range aiResp.TablesUsed {
+			name := baseName(t)
+			if !known[name] && !seen[name] {
+				seen[name] = true
+				warnings = append(warnings, fmt.Sprintf("tables_used menyebut tabel '%s' yang tidak ada di skema", name))
+			}
+		}
+	}
+
+	if aiResp.Source != SQLSourceCache && len(aiResp.TablesUsed) == 0 {
+		warnings = append(warnings, "LLM tidak menyebutkan tabel yang dipakai")
+	}
+	if sqlSelectStar.MatchString(aiResp.SQL) {
+		warnings = append(warnings, "query memakai SELECT *")
+	}
+	return warnings
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testSchemaSnapshot() *SchemaSnapshot {
+	return &SchemaSnapshot{Tables: []TableInfo{
+		{Name: "nasabah", Columns: []ColumnInfo{{Name: "id"}, {Name: "tanggal_lahir"}}},
+		{Name: "rekening", Columns: []ColumnInfo{{Name: "id"}, {Name: "saldo"}}},
+	}}
+}
+
+func TestScoreConfidence(t *testing.T) {
+	snap := testSchemaSnapshot()
+	clean := "SELECT COUNT(id) FROM nasabah"
+	tables := []string{"nasabah"}
+
+	tests := []struct {
+		name         string
+		resp         AISqlResponse
+		wantScore    float64
+		wantWarnings int
+		wantRAG      bool
+	}{
+		{
+			name:      "cache memakai skor kemiripan",
+			resp:      AISqlResponse{Source: SQLSourceCache, SQL: clean, CacheScore: 0.97},
+			wantScore: 0.97,
+		},
+		{
+			name:      "RAG + LLM tanpa voting dinormalisasi",
+			resp:      AISqlResponse{Source: SQLSourceRAG, SQL: clean, TablesUsed: tables, RAGScore: 0.8, LLMConfidence: 0.9},
+			wantScore: 0.857, // (0.8*0.3 + 0.9*0.4) / 0.7
+			wantRAG:   true,
+		},
+		{
+			name: "RAG + LLM + voting",
+			resp: AISqlResponse{Source: SQLSourceRAG, SQL: clean, TablesUsed: tables, RAGScore: 0.5, LLMConfidence: 1,
+				Vote: &VoteSummary{Confidence: 0.5}},
+			wantScore: 0.7, // 0.15 + 0.4 + 0.15
+			wantRAG:   true,
+		},
+		{
+			name:         "mode degraded: tanpa komponen RAG, satu peringatan",
+			resp:         AISqlResponse{Source: SQLSourceZeroShot, SQL: clean, TablesUsed: tables, RAGScore: 0.9, LLMConfidence: 0.8, Degraded: true},
+			wantScore:    0.7,
+			wantWarnings: 1,
+		},
+		{
+			name:         "setiap peringatan mengurangi 0.1",
+			resp:         AISqlResponse{Source: SQLSourceRAG, SQL: "SELECT * FROM transaksi", RAGScore: 1, LLMConfidence: 1},
+			wantScore:    0.7, // 1 - 3 peringatan
+			wantWarnings: 3,
+			wantRAG:      true,
+		},
+		{
+			name:         "skor tidak pernah negatif",
+			resp:         AISqlResponse{Source: SQLSourceRAG, SQL: "SELECT * FROM a JOIN b ON true JOIN c ON true", RAGScore: 0.1, LLMConfidence: 0.1},
+			wantScore:    0,
+			wantWarnings: 5,
+			wantRAG:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScoreConfidence(tt.resp, snap)
+			if got.Score != tt.wantScore {
+				t.Errorf("Score = %v, want %v", got.Score, tt.wantScore)
+			}
+			if len(got.Warnings) != tt.wantWarnings {
+				t.Errorf("Warnings = %v, want %d peringatan", got.Warnings, tt.wantWarnings)
+			}
+			if (got.RAGScore != nil) != tt.wantRAG {
+				t.Errorf("RAGScore = %v, want ada=%t", got.RAGScore, tt.wantRAG)
+			}
+			if got.Source != tt.resp.Source {
+				t.Errorf("Source = %q, want %q", got.Source, tt.resp.Source)
+			}
+		})
+	}
+}
+
+func TestSQLValidatorWarnings(t *testing.T) {
+	snap := testSchemaSnapshot()
+
+	tests := []struct {
+		name string
+		resp AISqlResponse
+		snap *SchemaSnapshot
+		want []string
+	}{
+		{
+			name: "bersih",
+			resp: AISqlResponse{SQL: `SELECT n.id FROM public.nasabah n JOIN "rekening" r ON r.id = n.id`, TablesUsed: []string{"nasabah", "rekening"}},
+			snap: snap,
+		},
+		{
+			name: "CTE dan EXTRACT(... FROM kolom) bukan tabel",
+			resp: AISqlResponse{
+				SQL:        "WITH aktif AS (SELECT id FROM nasabah) SELECT EXTRACT(YEAR FROM tanggal_lahir) FROM aktif",
+				TablesUsed: []string{"nasabah"},
+			},
+			snap: snap,
+		},
+		{
+			name: "tabel tidak dikenal dilaporkan sekali",
+			resp: AISqlResponse{SQL: "SELECT 1 FROM kartu JOIN kartu k2 ON true", TablesUsed: []string{"kartu"}},
+			snap: snap,
+			want: []string{"tabel 'kartu' tidak ada di skema"},
+		},
+		{
+			name: "tables_used tidak dikenal",
+			resp: AISqlResponse{SQL: "SELECT id FROM nasabah", TablesUsed: []string{"nasabah", "cabang"}},
+			snap: snap,
+			want: []string{"tables_used menyebut tabel 'cabang' yang tidak ada di skema"},
+		},
+		{
+			name: "tanpa snapshot hanya cek tables_used dan SELECT *",
+			resp: AISqlResponse{SQL: "SELECT DISTINCT * FROM apa_saja"},
+			want: []string{"LLM tidak menyebutkan tabel yang dipakai", "query memakai SELECT *"},
+		},
+		{
+			name: "cache tidak wajib tables_used",
+			resp: AISqlResponse{Source: SQLSourceCache, SQL: "SELECT id FROM nasabah"},
+			snap: snap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlValidatorWarnings(tt.resp, tt.snap); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sqlValidatorWarnings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLowConfidence(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+
+	tests := []struct {
+		name      string
+		threshold float64
+		c         *ConfidenceBreakdown
+		want      bool
+	}{
+		{"di bawah ambang", 0.6, &ConfidenceBreakdown{Score: 0.5}, true},
+		{"tepat di ambang", 0.6, &ConfidenceBreakdown{Score: 0.6}, false},
+		{"ambang nol menonaktifkan", 0, &ConfidenceBreakdown{Score: 0.1}, false},
+		{"tanpa breakdown", 0.6, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = &Config{ConfidenceThreshold: tt.threshold}
+			if got := tt.c.IsLowConfidence(); got != tt.want {
+				t.Errorf("IsLowConfidence = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,12 +16,16 @@ type Config struct {
 	DBMaxIdleConns    int
 	DBConnMaxLifetime time.Duration
 	DBPingTimeout     time.Duration
+	MetaSchema        string
+	MigrateOnStart    bool
 
 	// Groq AI
 	GroqAPIKey  string
 	GroqModel   string
 	GroqAPIURL  string
 	GroqTimeout time.Duration
+	// Endpoint daftar model untuk health check (default diturunkan dari GroqAPIURL)
+	GroqModelsURL string
 
 	// Google AI
 	GoogleAPIKey        string
@@ -28,8 +33,27 @@ type Config struct {
 	EmbeddingVectorSize int
 
 	// Ollama (local LLM)
-	OllamaURL   string
-	OllamaModel string
+	OllamaURL     string
+	OllamaModel   string
+	OllamaTimeout time.Duration
+
+	// LLM output contract
+	LLMRepairRetries int
+
+	// Prompt templates
+	PromptTemplatesDir   string
+	PromptDefaultVersion string
+	PromptRollout        string
+
+	// Multi-candidate generation
+	SQLCandidates            int
+	SQLCandidatesMax         int
+	SQLCandidateTemperatures []float32
+	SQLCandidateProviders    []string
+	SQLCandidateMaxRows      int
+
+	// Confidence scoring
+	ConfidenceThreshold float64
 
 	// Qdrant
 	QdrantGRPCHost        string
@@ -39,20 +63,76 @@ type Config struct {
 	QdrantCacheCollection string
 	QdrantDistanceMetric  string
 	QdrantTimeout         time.Duration
+	// Interval reconnect Qdrant selama mode degraded
+	QdrantReconnectInterval time.Duration
+
+	// Resilience (retry & circuit breaker untuk Groq, Ollama, Gemini, Qdrant)
+	HTTPRetryMax            int
+	HTTPRetryBaseDelay      time.Duration
+	HTTPRetryMaxDelay       time.Duration
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
 
 	// Cache
 	CacheSimilarityThreshold float32
 	CacheSearchLimit         uint64
 
 	// RAG
-	RAGSearchLimit uint64
+	RAGSearchLimit    uint64
+	RAGHybridEnabled  bool
+	RAGDenseWeight    float32
+	RAGSparseWeight   float32
+	RAGRRFK           int
+	RAGSparseMinScore float32
+	RAGDDLTopK        uint64
+	RAGDDLFKDepth     int
+	RAGDDLMaxTables   int
+	RAGMinDenseScore  float32
+
+	// Business dictionary
+	DictionaryRetrieval string
+	DictionaryTopK      uint64
+	DictionaryMinScore  float32
+
+	// Absurd / off-topic guard
+	AbsurdReloadInterval      time.Duration
+	OffTopicClassifierEnabled bool
+	OffTopicMinScore          float32
+	SuggestionCount           int
+	ClarificationEnabled      bool
+
+	// Seed rag_sql_examples
+	SeedExamplesDir     string
+	SeedExamplesOnStart bool
+
+	// Eval harness
+	EvalGoldenFile string
+
+	// Schema introspection
+	SchemaSampleValues      int
+	SchemaSampleMaxDistinct int
+	ReferenceTablePattern   string
+	ReferenceTableMaxRows   int
+	ReferenceTableRowLimit  int
+	SchemaRefreshInterval   time.Duration
+	SchemaNotifyChannel     string
 
 	// Server
 	ServerPort string
 	ServerHost string
 
+	// Health check
+	HealthCheckTimeout   time.Duration
+	SchemaSnapshotMaxAge time.Duration
+
 	// Query
-	QueryTimeout time.Duration
+	QueryTimeout   time.Duration
+	RequestTimeout time.Duration
+
+	// Debug output
+	DebugRoleHeader   string
+	DebugAllowedRoles []string
+	DebugToken        string
 
 	// Environment
 	AppEnv string
@@ -69,12 +149,15 @@ func LoadConfig() (*Config, error) {
 		DBMaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
 		DBConnMaxLifetime: time.Duration(getEnvAsInt("DB_CONN_MAX_LIFETIME_MINUTES", 5)) * time.Minute,
 		DBPingTimeout:     time.Duration(getEnvAsInt("DB_PING_TIMEOUT_SECONDS", 5)) * time.Second,
+		MetaSchema:        getEnv("META_SCHEMA", "ai_meta"),
+		MigrateOnStart:    getEnvAsBool("MIGRATE_ON_START", true),
 
 		// Groq AI
-		GroqAPIKey:  getEnv("GROQ_API_KEY", ""),
-		GroqModel:   getEnv("GROQ_MODEL", ""),
-		GroqAPIURL:  getEnv("GROQ_API_URL", ""),
-		GroqTimeout: time.Duration(getEnvAsInt("GROQ_TIMEOUT_SECONDS", 30)) * time.Second,
+		GroqAPIKey:    getEnv("GROQ_API_KEY", ""),
+		GroqModel:     getEnv("GROQ_MODEL", ""),
+		GroqAPIURL:    getEnv("GROQ_API_URL", ""),
+		GroqTimeout:   time.Duration(getEnvAsInt("GROQ_TIMEOUT_SECONDS", 30)) * time.Second,
+		GroqModelsURL: getEnv("GROQ_MODELS_URL", ""),
 
 		// Google AI
 		GoogleAPIKey:        getEnv("GOOGLE_API_KEY", ""),
@@ -82,31 +165,105 @@ func LoadConfig() (*Config, error) {
 		EmbeddingVectorSize: getEnvAsInt("EMBEDDING_VECTOR_SIZE", 768),
 
 		// Ollama (local LLM)
-		OllamaURL:   getEnv("OLLAMA_URL", ""),
-		OllamaModel: getEnv("OLLAMA_MODEL", ""),
+		OllamaURL:     getEnv("OLLAMA_URL", ""),
+		OllamaModel:   getEnv("OLLAMA_MODEL", ""),
+		OllamaTimeout: time.Duration(getEnvAsInt("OLLAMA_TIMEOUT_SECONDS", 60)) * time.Second,
+
+		// LLM output contract
+		LLMRepairRetries: getEnvAsInt("LLM_REPAIR_RETRIES", 1),
+
+		// Prompt templates
+		PromptTemplatesDir:   getEnv("PROMPT_TEMPLATES_DIR", "prompts"),
+		PromptDefaultVersion: getEnv("PROMPT_DEFAULT_VERSION", "v1"),
+		PromptRollout:        getEnv("PROMPT_ROLLOUT", ""),
+
+		// Multi-candidate generation
+		SQLCandidates:            getEnvAsInt("SQL_CANDIDATES", 1),
+		SQLCandidatesMax:         getEnvAsInt("SQL_CANDIDATES_MAX", 5),
+		SQLCandidateTemperatures: getEnvAsFloat32Slice("SQL_CANDIDATE_TEMPERATURES", []float32{0, 0.3, 0.6, 0.9}),
+		SQLCandidateProviders:    getEnvAsSlice("SQL_CANDIDATE_PROVIDERS", nil),
+		SQLCandidateMaxRows:      getEnvAsInt("SQL_CANDIDATE_MAX_ROWS", 1000),
+
+		// Confidence scoring
+		ConfidenceThreshold: float64(getEnvAsFloat32("CONFIDENCE_THRESHOLD", 0.5)),
 
 		// Qdrant
-		QdrantGRPCHost:        getEnv("QDRANT_GRPC_HOST", ""),
-		QdrantGRPCPort:        getEnvAsInt("QDRANT_GRPC_PORT", 6334),
-		QdrantURL:             getEnv("QDRANT_URL", ""),
-		QdrantCollectionName:  getEnv("QDRANT_COLLECTION_NAME", ""),
-		QdrantCacheCollection: getEnv("QDRANT_CACHE_COLLECTION", ""),
-		QdrantDistanceMetric:  getEnv("QDRANT_DISTANCE_METRIC", ""),
-		QdrantTimeout:         time.Duration(getEnvAsInt("QDRANT_TIMEOUT_SECONDS", 60)) * time.Second,
+		QdrantGRPCHost:          getEnv("QDRANT_GRPC_HOST", ""),
+		QdrantGRPCPort:          getEnvAsInt("QDRANT_GRPC_PORT", 6334),
+		QdrantURL:               getEnv("QDRANT_URL", ""),
+		QdrantCollectionName:    getEnv("QDRANT_COLLECTION_NAME", ""),
+		QdrantCacheCollection:   getEnv("QDRANT_CACHE_COLLECTION", ""),
+		QdrantDistanceMetric:    getEnv("QDRANT_DISTANCE_METRIC", ""),
+		QdrantTimeout:           time.Duration(getEnvAsInt("QDRANT_TIMEOUT_SECONDS", 60)) * time.Second,
+		QdrantReconnectInterval: time.Duration(getEnvAsInt("QDRANT_RECONNECT_INTERVAL_SECONDS", 15)) * time.Second,
+
+		// Resilience (retry & circuit breaker untuk Groq, Ollama, Gemini, Qdrant)
+		HTTPRetryMax:            getEnvAsInt("HTTP_RETRY_MAX", 2),
+		HTTPRetryBaseDelay:      time.Duration(getEnvAsInt("HTTP_RETRY_BASE_DELAY_MS", 200)) * time.Millisecond,
+		HTTPRetryMaxDelay:       time.Duration(getEnvAsInt("HTTP_RETRY_MAX_DELAY_MS", 5000)) * time.Millisecond,
+		CircuitBreakerThreshold: getEnvAsInt("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5),
+		CircuitBreakerCooldown:  time.Duration(getEnvAsInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second,
 
 		// Cache
 		CacheSimilarityThreshold: getEnvAsFloat32("CACHE_SIMILARITY_THRESHOLD", 0.95),
 		CacheSearchLimit:         uint64(getEnvAsInt("CACHE_SEARCH_LIMIT", 1)),
 
 		// RAG
-		RAGSearchLimit: uint64(getEnvAsInt("RAG_SEARCH_LIMIT", 7)),
+		RAGSearchLimit:    uint64(getEnvAsInt("RAG_SEARCH_LIMIT", 7)),
+		RAGHybridEnabled:  getEnvAsBool("RAG_HYBRID_ENABLED", true),
+		RAGDenseWeight:    getEnvAsFloat32("RAG_FUSION_DENSE_WEIGHT", 1.0),
+		RAGSparseWeight:   getEnvAsFloat32("RAG_FUSION_SPARSE_WEIGHT", 1.0),
+		RAGRRFK:           getEnvAsInt("RAG_RRF_K", 60),
+		RAGSparseMinScore: getEnvAsFloat32("RAG_SPARSE_MIN_SCORE", 0.25),
+		RAGDDLTopK:        uint64(getEnvAsInt("RAG_DDL_TOP_K", 5)),
+		RAGDDLFKDepth:     getEnvAsInt("RAG_DDL_FK_DEPTH", 1),
+		RAGDDLMaxTables:   getEnvAsInt("RAG_DDL_MAX_TABLES", 12),
+		RAGMinDenseScore:  getEnvAsFloat32("RAG_MIN_DENSE_SCORE", 0.45),
+
+		// Business dictionary
+		DictionaryRetrieval: getEnv("DICTIONARY_RETRIEVAL", "relevant"),
+		DictionaryTopK:      uint64(getEnvAsInt("DICTIONARY_TOP_K", 5)),
+		DictionaryMinScore:  getEnvAsFloat32("DICTIONARY_MIN_SCORE", 0.6),
+
+		// Absurd / off-topic guard
+		AbsurdReloadInterval:      time.Duration(getEnvAsInt("ABSURD_KEYWORDS_RELOAD_SECONDS", 60)) * time.Second,
+		OffTopicClassifierEnabled: getEnvAsBool("OFF_TOPIC_CLASSIFIER_ENABLED", false),
+		OffTopicMinScore:          getEnvAsFloat32("OFF_TOPIC_MIN_SCORE", 0.35),
+		SuggestionCount:           getEnvAsInt("SUGGESTION_COUNT", 3),
+		ClarificationEnabled:      getEnvAsBool("CLARIFICATION_ENABLED", true),
+
+		// Seed rag_sql_examples
+		SeedExamplesDir:     getEnv("SEED_EXAMPLES_DIR", "seeds/examples"),
+		SeedExamplesOnStart: getEnvAsBool("SEED_EXAMPLES_ON_START", true),
+
+		// Eval harness
+		EvalGoldenFile: getEnv("EVAL_GOLDEN_FILE", "evals/golden.yaml"),
+
+		// Schema introspection
+		SchemaSampleValues:      getEnvAsInt("SCHEMA_SAMPLE_VALUES", 3),
+		SchemaSampleMaxDistinct: getEnvAsInt("SCHEMA_SAMPLE_MAX_DISTINCT", 20),
+		ReferenceTablePattern:   getEnv("REFERENCE_TABLE_PATTERN", "master_%"),
+		ReferenceTableMaxRows:   getEnvAsInt("REFERENCE_TABLE_MAX_ROWS", 200),
+		ReferenceTableRowLimit:  getEnvAsInt("REFERENCE_TABLE_ROW_LIMIT", 50),
+		SchemaRefreshInterval:   time.Duration(getEnvAsInt("SCHEMA_REFRESH_INTERVAL_SECONDS", 300)) * time.Second,
+		SchemaNotifyChannel:     getEnv("SCHEMA_NOTIFY_CHANNEL", "schema_changed"),
 
 		// Server
 		ServerPort: getEnv("SERVER_PORT", ""),
 		ServerHost: getEnv("SERVER_HOST", ""),
 
+		// Health check
+		HealthCheckTimeout:   time.Duration(getEnvAsInt("HEALTH_CHECK_TIMEOUT_SECONDS", 3)) * time.Second,
+		SchemaSnapshotMaxAge: time.Duration(getEnvAsInt("SCHEMA_SNAPSHOT_MAX_AGE_SECONDS", 0)) * time.Second,
+
 		// Query
-		QueryTimeout: time.Duration(getEnvAsInt("QUERY_TIMEOUT_SECONDS", 10)) * time.Second,
+		QueryTimeout:   time.Duration(getEnvAsInt("QUERY_TIMEOUT_SECONDS", 10)) * time.Second,
+		RequestTimeout: time.Duration(getEnvAsInt("REQUEST_TIMEOUT_SECONDS", 45)) * time.Second,
+
+		// Debug output
+		DebugRoleHeader:   getEnv("DEBUG_ROLE_HEADER", "X-User-Role"),
+		DebugAllowedRoles: getEnvAsSlice("DEBUG_ALLOWED_ROLES", nil),
+		DebugToken:        getEnv("DEBUG_TOKEN", ""),
 
 		// Environment
 		AppEnv: getEnv("APP_ENV", ""),
@@ -174,3 +331,37 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return value
 }
+
+// getEnvAsSlice membaca daftar dipisah koma; entri kosong diabaikan.
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, part := range strings.Split(valueStr, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+func getEnvAsFloat32Slice(key string, defaultValue []float32) []float32 {
+	parts := getEnvAsSlice(key, nil)
+	if parts == nil {
+		return defaultValue
+	}
+	values := make([]float32, 0, len(parts))
+	for _, part := range parts {
+		value, err := strconv.ParseFloat(part, 32)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, float32(value))
+	}
+	return values
+}
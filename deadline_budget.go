@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Porsi sisa waktu request yang boleh dipakai setiap tahap pipeline /api/query. Porsi dihitung
+// dari sisa deadline saat tahap dimulai, sehingga tahap yang selesai cepat memberi waktu lebih
+// ke tahap berikutnya; deadline request tetap menjadi batas akhir semua tahap.
+const (
+	budgetShareClassify    = 0.15
+	budgetShareEmbedding   = 0.15
+	budgetShareCacheLookup = 0.10
+	budgetShareRAGSearch   = 0.15
+	budgetSharePromptBuild = 0.15
+	budgetShareLLM         = 0.80
+	budgetShareExecute     = 1.0
+
+	// Batas bawah per tahap agar tahap kecil tidak gagal hanya karena pembulatan porsi
+	minStageBudget = 500 * time.Millisecond
+)
+
+// stageContext menurunkan context untuk satu tahap dengan timeout = share × sisa waktu request.
+// Tanpa deadline di ctx (mis. CLI), tahap hanya ikut pembatalan ctx induk.
+func stageContext(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	remaining := time.Until(deadline)
+	budget := time.Duration(float64(remaining) * share)
+	if budget < minStageBudget {
+		budget = min(minStageBudget, remaining)
+	}
+	return context.WithTimeout(ctx, budget)
+}
+
+// handleRequestContextError menangani context request yang sudah selesai setelah sebuah tahap:
+// deadline habis dijawab 504, client yang memutus koneksi cukup di-log. Mengembalikan true jika
+// handler harus berhenti.
+func handleRequestContextError(w http.ResponseWriter, ctx context.Context, stage string) bool {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("⏱️ Deadline request habis saat %s (REQUEST_TIMEOUT_SECONDS=%s).", stage, AppConfig.RequestTimeout)
+		sendError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT", "Permintaan melebihi batas waktu pemrosesan")
+		return true
+	case errors.Is(err, context.Canceled):
+		log.Printf("Client memutus koneksi saat %s, pemrosesan dihentikan.", stage)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStageContext(t *testing.T) {
+	const tolerance = 50 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		remaining time.Duration
+		share     float64
+		want      time.Duration
+	}{
+		{"porsi dari sisa waktu", 10 * time.Second, budgetShareLLM, 8 * time.Second},
+		{"porsi penuh", 4 * time.Second, budgetShareExecute, 4 * time.Second},
+		{"porsi kecil dinaikkan ke batas bawah", 2 * time.Second, budgetShareCacheLookup, minStageBudget},
+		{"batas bawah tidak melewati deadline request", 300 * time.Millisecond, budgetShareClassify, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, cancel := context.WithTimeout(context.Background(), tt.remaining)
+			defer cancel()
+			ctx, cancelStage := stageContext(parent, tt.share)
+			defer cancelStage()
+
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("context tahap tidak punya deadline")
+			}
+			got := time.Until(deadline)
+			if got > tt.want+tolerance || got < tt.want-tolerance {
+				t.Errorf("budget = %s, want ~%s", got, tt.want)
+			}
+			if parentDeadline, _ := parent.Deadline(); deadline.After(parentDeadline) {
+				t.Errorf("deadline tahap %s melewati deadline request %s", deadline, parentDeadline)
+			}
+		})
+	}
+}
+
+func TestStageContextWithoutDeadline(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, cancelStage := stageContext(parent, budgetShareLLM)
+	defer cancelStage()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("context tanpa deadline tidak boleh diberi timeout")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("pembatalan context induk tidak diteruskan ke tahap")
+	}
+}
+
+func TestHandleRequestContextError(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{RequestTimeout: time.Second}
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantStop   bool
+		wantStatus int
+	}{
+		{"context masih aktif", context.Background(), false, http.StatusOK},
+		{"deadline habis dijawab 504", expired, true, http.StatusGatewayTimeout},
+		{"client memutus koneksi tanpa response", canceled, true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if got := handleRequestContextError(w, tt.ctx, "tes"); got != tt.wantStop {
+				t.Errorf("handleRequestContextError = %v, want %v", got, tt.wantStop)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+var (
+	ErrDictionaryNotFound  = errors.New("istilah tidak ditemukan")
+	ErrDictionaryConflict  = errors.New("istilah sudah diubah oleh proses lain (version tidak cocok)")
+	ErrDictionaryDuplicate = errors.New("istilah sudah ada")
+
+	sqlFragmentForbiddenRe = regexp.MustCompile(`(?i)\b(DROP|DELETE|INSERT|UPDATE|ALTER|TRUNCATE|CREATE|GRANT|REVOKE|COPY|EXECUTE|CALL)\b`)
+)
+
+// DictionaryItem adalah satu istilah bisnis beserta potongan logika SQL-nya.
+type DictionaryItem struct {
+	ID        int64     `json:"id"`
+	Istilah   string    `json:"istilah"`
+	Definisi  string    `json:"definisi_bisnis"`
+	LogikaSQL string    `json:"logika_sql"`
+	Version   int       `json:"version"`
+	IsActive  bool      `json:"is_active"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+}
+
+type DictionaryInput struct {
+	Istilah   string `json:"istilah"`
+	Definisi  string `json:"definisi_bisnis"`
+	LogikaSQL string `json:"logika_sql"`
+	// Version (opsional saat update) untuk optimistic locking.
+	Version   int    `json:"version,omitempty"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+type DictionaryHistoryEntry struct {
+	Version   int       `json:"version"`
+	Action    string    `json:"action"`
+	Istilah   string    `json:"istilah"`
+	Definisi  string    `json:"definisi_bisnis"`
+	LogikaSQL string    `json:"logika_sql"`
+	ChangedBy string    `json:"changed_by,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+const dictionaryColumns = `
+	id,
+	istilah,
+	definisi_bisnis,
+	logika_sql,
+	version,
+	is_active,
+	updated_at,
+	COALESCE(updated_by, '')`
+
+func scanDictionaryItem(row rowScanner) (DictionaryItem, error) {
+	var d DictionaryItem
+	err := row.Scan(&d.ID, &d.Istilah, &d.Definisi, &d.LogikaSQL, &d.Version, &d.IsActive, &d.UpdatedAt, &d.UpdatedBy)
+	return d, err
+}
+
+// validateSQLFragment memeriksa logika_sql sebagai potongan ekspresi/kondisi SQL: satu pernyataan,
+// tanpa komentar, tanpa kata kunci DDL/DML, tanda kurung dan kutip seimbang.
+func validateSQLFragment(fragment string) error {
+	f := strings.TrimSpace(fragment)
+	if f == "" {
+		return fmt.Errorf("logika_sql tidak boleh kosong")
+	}
+	if strings.Contains(f, ";") {
+		return fmt.Errorf("logika_sql tidak boleh mengandung ';'")
+	}
+	if strings.Contains(f, "--") || strings.Contains(f, "/*") {
+		return fmt.Errorf("logika_sql tidak boleh mengandung komentar")
+	}
+	if m := sqlFragmentForbiddenRe.FindString(f); m != "" {
+		return fmt.Errorf("logika_sql mengandung kata kunci terlarang: %s", strings.ToUpper(m))
+	}
+
+	depth := 0
+	inQuote := false
+	for _, r := range f {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("logika_sql: tanda kurung tidak seimbang")
+			}
+		}
+	}
+	if inQuote {
+		return fmt.Errorf("logika_sql: tanda kutip tidak ditutup")
+	}
+	if depth != 0 {
+		return fmt.Errorf("logika_sql: tanda kurung tidak seimbang")
+	}
+	return nil
+}
+
+func validateDictionaryInput(in *DictionaryInput) error {
+	in.Istilah = strings.TrimSpace(in.Istilah)
+	in.Definisi = strings.TrimSpace(in.Definisi)
+	in.LogikaSQL = strings.TrimSpace(in.LogikaSQL)
+	in.UpdatedBy = strings.TrimSpace(in.UpdatedBy)
+
+	if in.Istilah == "" || in.Definisi == "" {
+		return &ValidationError{Reason: "istilah dan definisi_bisnis wajib diisi"}
+	}
+	if err := validateSQLFragment(in.LogikaSQL); err != nil {
+		return &ValidationError{Reason: err.Error()}
+	}
+	return nil
+}
+
+// LoadDictionary mengembalikan semua istilah aktif (dipakai schema snapshot).
+func LoadDictionary(ctx context.Context) ([]DictionaryItem, error) {
+	return ListDictionary(ctx, false)
+}
+
+func ListDictionary(ctx context.Context, includeInactive bool) ([]DictionaryItem, error) {
+	if DbInstance == nil {
+		return nil, fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	rows, err := DbInstance.QueryContext(ctx, fmt.Sprintf(`
+	SELECT %s
+	FROM
+		%s
+	WHERE
+		$1 OR is_active
+	ORDER BY
+		istilah;
+	`, dictionaryColumns, metaTable("ai_dictionary")), includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca ai_dictionary: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]DictionaryItem, 0)
+	for rows.Next() {
+		d, err := scanDictionaryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, d)
+	}
+	return items, rows.Err()
+}
+
+func GetDictionaryItem(ctx context.Context, id int64) (DictionaryItem, error) {
+	d, err := scanDictionaryItem(DbInstance.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE id = $1", dictionaryColumns, metaTable("ai_dictionary")), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return d, ErrDictionaryNotFound
+	}
+	return d, err
+}
+
+func DictionaryHistory(ctx context.Context, id int64) ([]DictionaryHistoryEntry, error) {
+	rows, err := DbInstance.QueryContext(ctx, fmt.Sprintf(`
+	SELECT
+		version,
+		action,
+		istilah,
+		definisi_bisnis,
+		logika_sql,
+		COALESCE(changed_by, ''),
+		changed_at
+	FROM
+		%s
+	WHERE
+		dictionary_id = $1
+	ORDER BY
+		version DESC, id DESC;
+	`, metaTable("ai_dictionary_history")), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]DictionaryHistoryEntry, 0)
+	for rows.Next() {
+		var h DictionaryHistoryEntry
+		if err := rows.Scan(&h.Version, &h.Action, &h.Istilah, &h.Definisi, &h.LogikaSQL, &h.ChangedBy, &h.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+func recordDictionaryHistory(ctx context.Context, tx *sql.Tx, d DictionaryItem, action string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+	INSERT INTO %s
+		(dictionary_id, version, action, istilah, definisi_bisnis, logika_sql, changed_by)
+	VALUES
+		($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+	`, metaTable("ai_dictionary_history")), d.ID, d.Version, action, d.Istilah, d.Definisi, d.LogikaSQL, d.UpdatedBy)
+	return err
+}
+
+// dictionaryTermTaken memeriksa duplikasi istilah aktif (case-insensitive), kecuali excludeID.
+func dictionaryTermTaken(ctx context.Context, tx *sql.Tx, istilah string, excludeID int64) (bool, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+	SELECT EXISTS (
+		SELECT 1 FROM %s WHERE is_active AND lower(istilah) = lower($1) AND id <> $2
+	)
+	`, metaTable("ai_dictionary")), istilah, excludeID).Scan(&taken)
+	return taken, err
+}
+
+func CreateDictionaryItem(ctx context.Context, in DictionaryInput) (DictionaryItem, error) {
+	if err := validateDictionaryInput(&in); err != nil {
+		return DictionaryItem{}, err
+	}
+
+	tx, err := DbInstance.BeginTx(ctx, nil)
+	if err != nil {
+		return DictionaryItem{}, err
+	}
+	defer tx.Rollback()
+
+	if taken, err := dictionaryTermTaken(ctx, tx, in.Istilah, 0); err != nil {
+		return DictionaryItem{}, err
+	} else if taken {
+		return DictionaryItem{}, ErrDictionaryDuplicate
+	}
+
+	d, err := scanDictionaryItem(tx.QueryRowContext(ctx, fmt.Sprintf(`
+	INSERT INTO %s
+		(istilah, definisi_bisnis, logika_sql, updated_by)
+	VALUES
+		($1, $2, $3, NULLIF($4, ''))
+	RETURNING %s
+	`, metaTable("ai_dictionary"), dictionaryColumns), in.Istilah, in.Definisi, in.LogikaSQL, in.UpdatedBy))
+	if err != nil {
+		return d, fmt.Errorf("gagal menyimpan istilah: %w", err)
+	}
+	if err := recordDictionaryHistory(ctx, tx, d, "create"); err != nil {
+		return d, err
+	}
+	if err := tx.Commit(); err != nil {
+		return d, err
+	}
+
+	log.Printf("✅ Kamus: istilah '%s' ditambahkan (v%d).", d.Istilah, d.Version)
+	afterDictionaryChange(ctx, &d, nil)
+	return d, nil
+}
+
+func UpdateDictionaryItem(ctx context.Context, id int64, in DictionaryInput) (DictionaryItem, error) {
+	if err := validateDictionaryInput(&in); err != nil {
+		return DictionaryItem{}, err
+	}
+	return mutateDictionaryItem(ctx, id, in.Version, "update", func(tx *sql.Tx, current DictionaryItem) (DictionaryItem, error) {
+		if taken, err := dictionaryTermTaken(ctx, tx, in.Istilah, id); err != nil {
+			return current, err
+		} else if taken {
+			return current, ErrDictionaryDuplicate
+		}
+
+		return scanDictionaryItem(tx.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET
+			istilah = $1,
+			definisi_bisnis = $2,
+			logika_sql = $3,
+			updated_by = NULLIF($4, ''),
+			version = version + 1,
+			updated_at = now()
+		WHERE
+			id = $5
+		RETURNING %s
+		`, metaTable("ai_dictionary"), dictionaryColumns), in.Istilah, in.Definisi, in.LogikaSQL, in.UpdatedBy, id))
+	})
+}
+
+// DeleteDictionaryItem menonaktifkan istilah (soft delete) agar riwayatnya tetap tersimpan.
+func DeleteDictionaryItem(ctx context.Context, id int64, expectedVersion int, actor string) (DictionaryItem, error) {
+	return mutateDictionaryItem(ctx, id, expectedVersion, "delete", func(tx *sql.Tx, current DictionaryItem) (DictionaryItem, error) {
+		return scanDictionaryItem(tx.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET
+			is_active = false,
+			updated_by = NULLIF($1, ''),
+			version = version + 1,
+			updated_at = now()
+		WHERE
+			id = $2
+		RETURNING %s
+		`, metaTable("ai_dictionary"), dictionaryColumns), strings.TrimSpace(actor), id))
+	})
+}
+
+// mutateDictionaryItem mengunci baris aktif, memeriksa version (jika expectedVersion > 0),
+// menjalankan mutate, mencatat riwayat, lalu menyinkronkan RAG & snapshot.
+func mutateDictionaryItem(ctx context.Context, id int64, expectedVersion int, action string, mutate func(tx *sql.Tx, current DictionaryItem) (DictionaryItem, error)) (DictionaryItem, error) {
+	tx, err := DbInstance.BeginTx(ctx, nil)
+	if err != nil {
+		return DictionaryItem{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := scanDictionaryItem(tx.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE id = $1 AND is_active FOR UPDATE", dictionaryColumns, metaTable("ai_dictionary")), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return current, ErrDictionaryNotFound
+	}
+	if err != nil {
+		return current, err
+	}
+	if expectedVersion > 0 && expectedVersion != current.Version {
+		return current, ErrDictionaryConflict
+	}
+
+	d, err := mutate(tx, current)
+	if err != nil {
+		return d, err
+	}
+	if err := recordDictionaryHistory(ctx, tx, d, action); err != nil {
+		return d, err
+	}
+	if err := tx.Commit(); err != nil {
+		return d, err
+	}
+
+	log.Printf("✅ Kamus: istilah '%s' (%s) -> v%d.", d.Istilah, action, d.Version)
+	if action == "delete" {
+		afterDictionaryChange(ctx, nil, &current)
+	} else {
+		afterDictionaryChange(ctx, &d, &current)
+	}
+	return d, nil
+}
+
+// afterDictionaryChange menerapkan perubahan kamus ke collection RAG (tanpa retrain) dan
+// memperbarui schema snapshot. Kegagalan hanya dicatat; retrain berikutnya akan menyamakan.
+func afterDictionaryChange(ctx context.Context, current, previous *DictionaryItem) {
+	var item, prev *ragItem
+	if current != nil {
+		it := dictionaryRagItem(*current)
+		item = &it
+	}
+	if previous != nil {
+		it := dictionaryRagItem(*previous)
+		prev = &it
+	}
+	if err := syncRAGItem(ctx, item, prev); err != nil {
+		log.Printf("PERINGATAN: Gagal menyinkronkan istilah kamus ke RAG: %v", err)
+	}
+
+	if _, err := RefreshSchemaSnapshot(ctx); err != nil {
+		log.Printf("PERINGATAN: Gagal refresh schema snapshot setelah perubahan kamus: %v", err)
+	}
+}
+
+func dictionaryLine(d DictionaryItem) string {
+	return fmt.Sprintf("- \"%s\" bermakna: %s. (SQL Logic Wajib: `%s`)", d.Istilah, d.Definisi, d.LogikaSQL)
+}
+
+// renderBusinessDictionary menyusun bagian kamus istilah untuk prompt LLM.
+func renderBusinessDictionary(items []DictionaryItem) string {
+	if len(items) == 0 {
+		return "(Tidak ada istilah bisnis yang relevan dengan pertanyaan ini)"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("== KAMUS ISTILAH BISNIS (PRIORITAS TINGGI) ==\n")
+	builder.WriteString("Gunakan logika ini jika user menyebut kata kunci berikut:\n")
+	for _, d := range items {
+		builder.WriteString(dictionaryLine(d))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// SelectRelevantDictionary memilih istilah yang relevan dengan prompt: istilah yang disebut
+// langsung (kata utuh), ditambah istilah terdekat secara embedding dari collection RAG.
+func SelectRelevantDictionary(ctx context.Context, items []DictionaryItem, prompt string, promptVector []float32) []DictionaryItem {
+	if AppConfig == nil || AppConfig.DictionaryRetrieval == "all" || len(items) == 0 {
+		return items
+	}
+
+	selected := make(map[int64]bool)
+	normalizedPrompt := " " + normalizePromptKey(prompt) + " "
+	for _, d := range items {
+		term := normalizePromptKey(d.Istilah)
+		if term != "" && strings.Contains(normalizedPrompt, " "+term+" ") {
+			selected[d.ID] = true
+		}
+	}
+
+	if AppConfig.DictionaryTopK > 0 && promptVector != nil && qdrantClient != nil {
+		limit := AppConfig.DictionaryTopK
+		threshold := AppConfig.DictionaryMinScore
+		points, err := qdrantQuery(ctx, &pb.QueryPoints{
+			CollectionName: ragReadCollection(),
+			Query:          pb.NewQuery(promptVector...),
+			WithPayload:    pb.NewWithPayload(true),
+			Limit:          &limit,
+			ScoreThreshold: &threshold,
+			Filter:         categoryFilter("dictionary"),
+		})
+		if err != nil {
+			log.Printf("PERINGATAN: Pencarian kamus di Qdrant gagal, hanya memakai keyword: %v", err)
+		}
+		for _, p := range points {
+			if v, ok := p.GetPayload()["dictionary_id"]; ok {
+				selected[v.GetIntegerValue()] = true
+			}
+		}
+	}
+
+	relevant := make([]DictionaryItem, 0, len(selected))
+	for _, d := range items {
+		if selected[d.ID] {
+			relevant = append(relevant, d)
+		}
+	}
+	log.Printf("📖 Kamus: %d dari %d istilah relevan dengan prompt.", len(relevant), len(items))
+	return relevant
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestValidateSQLFragment(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		wantErr  bool
+	}{
+		{"kondisi sederhana", "status_rekening = 'AKTIF'", false},
+		{"fungsi dan kurung bersarang", "COALESCE(SUM(saldo), 0) > (SELECT AVG(saldo) FROM tabungan)", false},
+		{"kurung di dalam string diabaikan", "keterangan LIKE '%(lama%'", false},
+		{"kata terlarang di dalam nama kolom", "updated_at >= now() - interval '7 days'", false},
+		{"kosong", "   ", true},
+		{"titik koma", "saldo > 0; DROP TABLE nasabah", true},
+		{"komentar baris", "saldo > 0 -- bypass", true},
+		{"komentar blok", "saldo > 0 /* x */", true},
+		{"DML", "id IN (DELETE FROM nasabah RETURNING id)", true},
+		{"kata kunci huruf kecil", "exists (select 1 from x) or truncate", true},
+		{"kurung tutup berlebih", "(saldo > 0))", true},
+		{"kurung buka berlebih", "((saldo > 0)", true},
+		{"kurung tutup sebelum buka", ")saldo > 0(", true},
+		{"kutip tidak ditutup", "nama = 'budi", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSQLFragment(tt.fragment)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSQLFragment(%q) error = %v, wantErr %t", tt.fragment, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectRelevantDictionaryKeywords(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+
+	items := []DictionaryItem{
+		{ID: 1, Istilah: "NPL"},
+		{ID: 2, Istilah: "Kredit Macet"},
+		{ID: 3, Istilah: "DPK"},
+	}
+
+	tests := []struct {
+		name      string
+		retrieval string
+		prompt    string
+		wantIDs   []int64
+	}{
+		{"kata utuh tidak peka huruf besar", "relevant", "berapa rasio npl bulan ini?", []int64{1}},
+		{"istilah beberapa kata", "relevant", "daftar KREDIT macet per cabang", []int64{2}},
+		{"bukan kata utuh", "relevant", "tampilkan dpknya", []int64{}},
+		{"mode all mengirim semua istilah", "all", "apa saja", []int64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = &Config{DictionaryRetrieval: tt.retrieval}
+			got := SelectRelevantDictionary(context.Background(), items, tt.prompt, nil)
+			ids := []int64{}
+			for _, d := range got {
+				ids = append(ids, d.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("SelectRelevantDictionary(%q) = %v, want %v", tt.prompt, ids, tt.wantIDs)
+			}
+		})
+	}
+}
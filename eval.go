@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EvalCase adalah satu pertanyaan di golden set. Minimal salah satu dari ExpectedSQL atau
+// ExpectedRows harus diisi; jika keduanya ada, ExpectedRows yang dipakai sebagai acuan hasil.
+type EvalCase struct {
+	ID           string          `json:"id" yaml:"id"`
+	Prompt       string          `json:"prompt" yaml:"prompt"`
+	ExpectedSQL  string          `json:"expected_sql,omitempty" yaml:"expected_sql"`
+	ExpectedRows [][]interface{} `json:"expected_rows,omitempty" yaml:"expected_rows"`
+}
+
+type EvalCaseResult struct {
+	ID            string  `json:"id"`
+	Prompt        string  `json:"prompt"`
+	GeneratedSQL  string  `json:"generated_sql,omitempty"`
+	PromptVersion string  `json:"prompt_version,omitempty"`
+	Cached        bool    `json:"cached"`
+	Source        string  `json:"source,omitempty"`
+	Confidence    float64 `json:"confidence"`
+	ExactMatch    bool    `json:"exact_match"`
+	ExecMatch     bool    `json:"exec_match"`
+	Error         string  `json:"error,omitempty"`
+	LatencyMs     float64 `json:"latency_ms"`
+}
+
+type EvalReport struct {
+	Model         string           `json:"model"`
+	PromptVersion string           `json:"prompt_version,omitempty"`
+	UseCache      bool             `json:"use_cache"`
+	StartedAt     time.Time        `json:"started_at"`
+	Duration      string           `json:"duration"`
+	Total         int              `json:"total"`
+	ExecAccuracy  float64          `json:"execution_accuracy"`
+	ExactMatch    float64          `json:"exact_match"`
+	CacheHitRate  float64          `json:"cache_hit_rate"`
+	ErrorRate     float64          `json:"error_rate"`
+	LatencyAvgMs  float64          `json:"latency_avg_ms"`
+	LatencyP50Ms  float64          `json:"latency_p50_ms"`
+	LatencyP95Ms  float64          `json:"latency_p95_ms"`
+	Cases         []EvalCaseResult `json:"cases"`
+}
+
+// LoadEvalCases membaca golden set dari file JSON atau YAML berisi daftar EvalCase.
+func LoadEvalCases(path string) ([]EvalCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca golden set '%s': %w", path, err)
+	}
+
+	var cases []EvalCase
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &cases)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cases)
+	default:
+		return nil, fmt.Errorf("format golden set tidak didukung: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("golden set '%s' tidak valid: %w", path, err)
+	}
+
+	for i := range cases {
+		if strings.TrimSpace(cases[i].Prompt) == "" {
+			return nil, fmt.Errorf("golden set: case #%d tidak punya prompt", i+1)
+		}
+		if strings.TrimSpace(cases[i].ExpectedSQL) == "" && cases[i].ExpectedRows == nil {
+			return nil, fmt.Errorf("golden set: case '%s' butuh expected_sql atau expected_rows", cases[i].Prompt)
+		}
+		if cases[i].ID == "" {
+			cases[i].ID = fmt.Sprintf("case_%03d", i+1)
+		}
+	}
+	return cases, nil
+}
+
+// RunEval menjalankan pipeline (RAG -> LLM) untuk setiap case lalu membandingkan hasil eksekusi
+// SQL yang dihasilkan dengan hasil yang diharapkan. Semantic cache hanya dipakai jika
+// opts.SkipCache false; tanpa itu run kedua kebanyakan mengukur cache hit, bukan model.
+func RunEval(ctx context.Context, cases []EvalCase, opts SQLGenOptions) EvalReport {
+	report := EvalReport{
+		Model:         evalModelName(),
+		PromptVersion: opts.PromptVersion,
+		UseCache:      !opts.SkipCache,
+		StartedAt:     time.Now(),
+		Total:         len(cases),
+	}
+
+	var latencies []float64
+	var execOK, exactOK, cached, failed int
+	for i, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		res := runEvalCase(ctx, c, opts)
+		report.Cases = append(report.Cases, res)
+		latencies = append(latencies, res.LatencyMs)
+
+		if res.ExecMatch {
+			execOK++
+		}
+		if res.ExactMatch {
+			exactOK++
+		}
+		if res.Cached {
+			cached++
+		}
+		if res.Error != "" {
+			failed++
+		}
+		log.Printf("Eval [%d/%d] %s: exec_match=%t exact=%t cached=%t", i+1, len(cases), c.ID, res.ExecMatch, res.ExactMatch, res.Cached)
+	}
+
+	if n := float64(len(report.Cases)); n > 0 {
+		report.ExecAccuracy = float64(execOK) / n
+		report.ExactMatch = float64(exactOK) / n
+		report.CacheHitRate = float64(cached) / n
+		report.ErrorRate = float64(failed) / n
+	}
+	report.LatencyAvgMs, report.LatencyP50Ms, report.LatencyP95Ms = latencyStats(latencies)
+	report.Duration = time.Since(report.StartedAt).Round(time.Millisecond).String()
+	return report
+}
+
+func runEvalCase(ctx context.Context, c EvalCase, opts SQLGenOptions) EvalCaseResult {
+	res := EvalCaseResult{ID: c.ID, Prompt: c.Prompt}
+	prompt := strings.ToLower(strings.TrimSpace(c.Prompt))
+
+	start := time.Now()
+	aiResp, err := GetSQL(ctx, prompt, opts)
+	res.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if aiResp.IsAmbiguous {
+		res.Error = "pipeline menilai pertanyaan ambigu"
+		return res
+	}
+	res.GeneratedSQL = aiResp.SQL
+	res.PromptVersion = aiResp.PromptVersion
+	res.Cached = aiResp.IsCached
+	res.Source = aiResp.Source
+	if aiResp.Confidence != nil {
+		res.Confidence = aiResp.Confidence.Score
+	}
+
+	if c.ExpectedSQL != "" {
+		res.ExactMatch = normalizeSQLForCompare(aiResp.SQL) == normalizeSQLForCompare(c.ExpectedSQL)
+	}
+
+	expected := c.ExpectedRows
+	if expected == nil {
+		want, err := ExecuteDynamicQuery(ctx, c.ExpectedSQL, nil)
+		if err != nil {
+			res.Error = fmt.Sprintf("expected_sql gagal dieksekusi: %v", err)
+			return res
+		}
+		expected = want.Rows
+	}
+
+	got, err := ExecuteDynamicQuery(ctx, aiResp.SQL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.ExecMatch = sameResultSet(got.Rows, expected)
+	return res
+}
+
+func evalModelName() string {
+	if AppConfig == nil {
+		return ""
+	}
+	if AppConfig.OllamaURL != "" {
+		return fmt.Sprintf("ollama:%s (fallback groq:%s)", AppConfig.OllamaModel, AppConfig.GroqModel)
+	}
+	return "groq:" + AppConfig.GroqModel
+}
+
+// normalizeSQLForCompare: huruf kecil, tanpa komentar, spasi dirapikan, tanpa titik koma akhir.
+func normalizeSQLForCompare(query string) string {
+	var lines []string
+	for _, line := range strings.Split(query, "\n") {
+		if idx := strings.Index(line, "--"); idx != -1 {
+			line = line[:idx]
+		}
+		lines = append(lines, line)
+	}
+	q := strings.ToLower(strings.Join(strings.Fields(strings.Join(lines, " ")), " "))
+	return strings.TrimSpace(strings.TrimRight(q, "; "))
+}
+
+// sameResultSet membandingkan dua result set tanpa memperhatikan urutan baris (multiset)
+// maupun nama kolom.
+func sameResultSet(got, want [][]interface{}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	a, b := resultRowKeys(got), resultRowKeys(want)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func resultRowKeys(rows [][]interface{}) []string {
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, v := range row {
+			cells[j] = normalizeResultValue(v)
+		}
+		keys[i] = strings.Join(cells, "\x1f")
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalizeResultValue menyamakan representasi nilai dari database dan dari file golden
+// (mis. NUMERIC "10.00" dari Postgres vs 10 dari YAML).
+func normalizeResultValue(v interface{}) string {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		s = string(val)
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	default:
+		s = fmt.Sprintf("%v", val)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return s
+}
+
+func latencyStats(latencies []float64) (avg, p50, p95 float64) {
+	if len(latencies) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, l := range sorted {
+		sum += l
+	}
+	percentile := func(p float64) float64 {
+		idx := int(p*float64(len(sorted)-1) + 0.5)
+		return sorted[idx]
+	}
+	return sum / float64(len(sorted)), percentile(0.50), percentile(0.95)
+}
+
+// WriteEvalMarkdown menulis ringkasan dan tabel per case dalam format Markdown.
+func WriteEvalMarkdown(w io.Writer, report EvalReport) error {
+	pct := func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) }
+
+	fmt.Fprintf(w, "# Eval Text-to-SQL\n\n")
+	fmt.Fprintf(w, "- Model: `%s`\n", report.Model)
+	if report.PromptVersion != "" {
+		fmt.Fprintf(w, "- Template prompt: `%s`\n", report.PromptVersion)
+	}
+	fmt.Fprintf(w, "- Waktu: %s (durasi %s)\n", report.StartedAt.Format(time.RFC3339), report.Duration)
+	if report.UseCache {
+		fmt.Fprintf(w, "- Semantic cache: dipakai\n")
+	} else {
+		fmt.Fprintf(w, "- Semantic cache: dilewati\n")
+	}
+	fmt.Fprintf(w, "- Jumlah case: %d\n\n", report.Total)
+
+	fmt.Fprintln(w, "| Metrik | Nilai |")
+	fmt.Fprintln(w, "|--------|-------|")
+	fmt.Fprintf(w, "| Execution accuracy | %s |\n", pct(report.ExecAccuracy))
+	fmt.Fprintf(w, "| Exact match | %s |\n", pct(report.ExactMatch))
+	fmt.Fprintf(w, "| Cache hit rate | %s |\n", pct(report.CacheHitRate))
+	fmt.Fprintf(w, "| Error rate | %s |\n", pct(report.ErrorRate))
+	fmt.Fprintf(w, "| Latency avg / p50 / p95 | %.0f / %.0f / %.0f ms |\n\n",
+		report.LatencyAvgMs, report.LatencyP50Ms, report.LatencyP95Ms)
+
+	fmt.Fprintln(w, "| ID | Exec | Exact | Cache | Latency (ms) | Error |")
+	fmt.Fprintln(w, "|----|------|-------|-------|--------------|-------|")
+	mark := func(b bool) string {
+		if b {
+			return "✅"
+		}
+		return "❌"
+	}
+	for _, c := range report.Cases {
+		_, err := fmt.Fprintf(w, "| %s | %s | %s | %t | %.0f | %s |\n",
+			c.ID, mark(c.ExecMatch), mark(c.ExactMatch), c.Cached, c.LatencyMs,
+			strings.ReplaceAll(oneLine(c.Error), "|", "\\|"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSameResultSet(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 7, 0, 0, 0, time.FixedZone("WIB", 7*3600))
+
+	tests := []struct {
+		name string
+		got  [][]interface{}
+		want [][]interface{}
+		same bool
+	}{
+		{"identik", [][]interface{}{{1, "a"}}, [][]interface{}{{1, "a"}}, true},
+		{"urutan baris berbeda", [][]interface{}{{1, "a"}, {2, "b"}}, [][]interface{}{{2, "b"}, {1, "a"}}, true},
+		{"numeric postgres vs angka yaml", [][]interface{}{{[]byte("10.00")}}, [][]interface{}{{10}}, true},
+		{"int64 vs float", [][]interface{}{{int64(5)}}, [][]interface{}{{5.0}}, true},
+		{"null", [][]interface{}{{nil}}, [][]interface{}{{nil}}, true},
+		{"null vs string kosong", [][]interface{}{{nil}}, [][]interface{}{{""}}, false},
+		{"timestamp dinormalisasi ke UTC", [][]interface{}{{ts}}, [][]interface{}{{ts.UTC()}}, true},
+		{"jumlah baris berbeda", [][]interface{}{{1}, {1}}, [][]interface{}{{1}}, false},
+		{"duplikat dihitung (multiset)", [][]interface{}{{1}, {1}, {2}}, [][]interface{}{{1}, {2}, {2}}, false},
+		{"nilai berbeda", [][]interface{}{{"x"}}, [][]interface{}{{"y"}}, false},
+		{"keduanya kosong", nil, [][]interface{}{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameResultSet(tt.got, tt.want); got != tt.same {
+				t.Errorf("sameResultSet(%v, %v) = %t, want %t", tt.got, tt.want, got, tt.same)
+			}
+		})
+	}
+}
+
+func TestNormalizeSQLForCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"huruf besar dan spasi", "SELECT  *\nFROM nasabah;", "select * from nasabah"},
+		{"komentar dibuang", "-- jumlah nasabah\nSELECT COUNT(*) FROM nasabah -- semua", "select count(*) from nasabah"},
+		{"titik koma akhir", "select 1 ;", "select 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := normalizeSQLForCompare(tt.a), normalizeSQLForCompare(tt.b); got != want {
+				t.Errorf("normalizeSQLForCompare(%q) = %q, want %q", tt.a, got, want)
+			}
+		})
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            []float64
+		avg, p50, p95 float64
+	}{
+		{"kosong", nil, 0, 0, 0},
+		{"satu nilai", []float64{42}, 42, 42, 42},
+		{"belum terurut", []float64{30, 10, 20}, 20, 20, 30},
+		{"sepuluh nilai", []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, 55, 60, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avg, p50, p95 := latencyStats(tt.in)
+			if avg != tt.avg || p50 != tt.p50 || p95 != tt.p95 {
+				t.Errorf("latencyStats(%v) = (%v, %v, %v), want (%v, %v, %v)", tt.in, avg, p50, p95, tt.avg, tt.p50, tt.p95)
+			}
+		})
+	}
+}
+
+func TestLatencyStatsDoesNotMutateInput(t *testing.T) {
+	in := []float64{3, 1, 2}
+	latencyStats(in)
+	if in[0] != 3 || in[1] != 1 || in[2] != 2 {
+		t.Errorf("input diubah: %v", in)
+	}
+}
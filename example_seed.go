@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const exampleSourceBuiltin = "builtin"
+
+// SeedExample adalah satu contoh SQL dari sumber seed (built-in atau file YAML/JSON).
+type SeedExample struct {
+	Prompt string `json:"prompt" yaml:"prompt"`
+	SQL    string `json:"sql" yaml:"sql"`
+	Source string `json:"-" yaml:"-"`
+}
+
+type SeedResult struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+}
+
+// normalizePromptKey dipakai untuk de-duplikasi: huruf kecil, tanpa prefix "-- Pertanyaan:",
+// tanpa tanda baca, spasi dirapikan.
+func normalizePromptKey(prompt string) string {
+	p := strings.ToLower(strings.TrimSpace(prompt))
+	p = strings.TrimPrefix(p, "-- pertanyaan:")
+	p = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return r
+		}
+		return ' '
+	}, p)
+	return strings.Join(strings.Fields(p), " ")
+}
+
+// builtinSeedExamples mengurai sqlContekan (training_data.go) menjadi pasangan prompt & SQL.
+func builtinSeedExamples() []SeedExample {
+	var examples []SeedExample
+	for _, raw := range sqlContekan {
+		var prompt string
+		var sqlLines []string
+		for _, line := range strings.Split(raw, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "-- Pertanyaan:") {
+				prompt = cleanExamplePrompt(trimmed)
+				continue
+			}
+			if trimmed != "" {
+				sqlLines = append(sqlLines, trimmed)
+			}
+		}
+		if prompt == "" || len(sqlLines) == 0 {
+			continue
+		}
+		examples = append(examples, SeedExample{
+			Prompt: prompt,
+			SQL:    strings.Join(sqlLines, "\n"),
+			Source: exampleSourceBuiltin,
+		})
+	}
+	return examples
+}
+
+// loadSeedExampleFiles membaca semua file *.json, *.yaml, *.yml di dir.
+// Setiap file berisi daftar objek {prompt, sql}. Direktori yang tidak ada diabaikan.
+func loadSeedExampleFiles(dir string) ([]SeedExample, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca direktori seed '%s': %w", dir, err)
+	}
+
+	var examples []SeedExample
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		var fileExamples []SeedExample
+		if ext == ".json" {
+			err = json.Unmarshal(data, &fileExamples)
+		} else {
+			err = yaml.Unmarshal(data, &fileExamples)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("file seed '%s' tidak valid: %w", name, err)
+		}
+
+		for _, ex := range fileExamples {
+			ex.Source = "file:" + name
+			examples = append(examples, ex)
+		}
+	}
+	return examples, nil
+}
+
+// SeedSqlExamples meng-upsert contoh built-in dan contoh dari direktori seed ke rag_sql_examples.
+// Contoh dengan prompt (ter-normalisasi) yang sudah ada dari sumber lain (mis. koreksi manual) tidak ditimpa.
+func SeedSqlExamples(ctx context.Context, dir string) (SeedResult, error) {
+	var result SeedResult
+	if DbInstance == nil {
+		return result, fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	fileExamples, err := loadSeedExampleFiles(dir)
+	if err != nil {
+		return result, err
+	}
+	seeds := append(builtinSeedExamples(), fileExamples...)
+
+	type existingRow struct {
+		ID     int64
+		SQL    string
+		Source string
+	}
+	rows, err := DbInstance.QueryContext(ctx, fmt.Sprintf(`
+	SELECT
+		id,
+		prompt_example,
+		sql_example,
+		COALESCE(source, '')
+	FROM
+		%s;
+	`, metaTable("rag_sql_examples")))
+	if err != nil {
+		return result, fmt.Errorf("gagal membaca rag_sql_examples: %w", err)
+	}
+	existing := make(map[string]existingRow)
+	for rows.Next() {
+		var row existingRow
+		var prompt string
+		if err := rows.Scan(&row.ID, &prompt, &row.SQL, &row.Source); err != nil {
+			rows.Close()
+			return result, err
+		}
+		existing[normalizePromptKey(cleanExamplePrompt(prompt))] = row
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	seen := make(map[string]bool)
+	for _, seed := range seeds {
+		prompt := strings.TrimSpace(seed.Prompt)
+		sqlQuery := strings.TrimSpace(seed.SQL)
+		key := normalizePromptKey(prompt)
+		if key == "" || sqlQuery == "" || seen[key] {
+			result.Skipped++
+			continue
+		}
+		seen[key] = true
+
+		if err := validateReadOnlySQL(sqlQuery); err != nil {
+			log.Printf("Seed dilewati (%s) '%s': %v", seed.Source, prompt, err)
+			result.Skipped++
+			continue
+		}
+
+		row, ok := existing[key]
+		switch {
+		case !ok:
+			_, err = DbInstance.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s
+				(prompt_example, sql_example, source)
+			VALUES
+				($1, $2, $3)
+			`, metaTable("rag_sql_examples")), fmt.Sprintf("-- Pertanyaan: \"%s\"", prompt), sqlQuery, seed.Source)
+			result.Inserted++
+		case row.Source != seed.Source:
+			result.Skipped++
+		case row.SQL != sqlQuery:
+			_, err = DbInstance.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET sql_example = $1 WHERE id = $2
+			`, metaTable("rag_sql_examples")), sqlQuery, row.ID)
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+		if err != nil {
+			return result, fmt.Errorf("gagal upsert contoh '%s': %w", prompt, err)
+		}
+	}
+
+	log.Printf("✅ Seed rag_sql_examples: %d baru, %d diperbarui, %d tetap, %d dilewati.",
+		result.Inserted, result.Updated, result.Unchanged, result.Skipped)
+
+	// Contoh baru langsung dipakai saran & retrieval BM25 tanpa menunggu retrain
+	if result.Inserted > 0 || result.Updated > 0 {
+		if err := RefreshSparseIndex(); err != nil {
+			log.Printf("PERINGATAN: Gagal membangun ulang indeks BM25 setelah seed: %v", err)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizePromptKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"huruf besar kecil", "Ada berapa nasabah?", "ada berapa NASABAH"},
+		{"tanda baca dan spasi", "  tampilkan   semua nasabah!!! ", "tampilkan semua nasabah"},
+		{"prefix komentar contekan", "-- Pertanyaan: apa saja jenis rekening yang ada?", "apa saja jenis rekening yang ada"},
+		{"tanda baca di tengah kata", "siapa nasabah dengan cif CIF-00001?", "siapa nasabah dengan cif cif 00001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ka, kb := normalizePromptKey(tt.a), normalizePromptKey(tt.b)
+			if ka != kb {
+				t.Errorf("normalizePromptKey(%q) = %q, normalizePromptKey(%q) = %q, harus sama", tt.a, ka, tt.b, kb)
+			}
+		})
+	}
+
+	if a, b := normalizePromptKey("saldo tabungan"), normalizePromptKey("saldo deposito"); a == b {
+		t.Errorf("prompt berbeda menghasilkan key sama: %q", a)
+	}
+	if got := normalizePromptKey("?!"); got != "" {
+		t.Errorf("prompt hanya tanda baca = %q, want kosong", got)
+	}
+}
+
+func TestBuiltinSeedExamplesParseAllContekan(t *testing.T) {
+	examples := builtinSeedExamples()
+	if len(examples) != len(sqlContekan) {
+		t.Fatalf("builtinSeedExamples menghasilkan %d contoh dari %d entri sqlContekan", len(examples), len(sqlContekan))
+	}
+	for i, ex := range examples {
+		if strings.Contains(ex.Prompt, `"`) || strings.HasPrefix(ex.Prompt, "--") || ex.Prompt == "" {
+			t.Errorf("contoh %d: prompt tidak bersih: %q", i, ex.Prompt)
+		}
+		if normalizePromptKey(ex.Prompt) == "" {
+			t.Errorf("contoh %d: key kosong untuk prompt %q", i, ex.Prompt)
+		}
+		upper := strings.ToUpper(ex.SQL)
+		if !strings.HasPrefix(upper, "SELECT") && !strings.HasPrefix(upper, "WITH") {
+			t.Errorf("contoh %d (%q): SQL tidak diawali SELECT/WITH: %q", i, ex.Prompt, ex.SQL)
+		}
+		if strings.Contains(ex.SQL, "-- Pertanyaan:") {
+			t.Errorf("contoh %d: komentar pertanyaan ikut ke SQL", i)
+		}
+		if ex.Source != exampleSourceBuiltin {
+			t.Errorf("contoh %d: source = %q, want %q", i, ex.Source, exampleSourceBuiltin)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FeedbackPending  = "pending"
+	FeedbackApproved = "approved"
+	FeedbackRejected = "rejected"
+
+	exampleSourceFeedback = "feedback"
+)
+
+var (
+	ErrFeedbackNotFound   = errors.New("feedback tidak ditemukan")
+	ErrFeedbackNotPending = errors.New("feedback sudah direview")
+)
+
+// FeedbackSubmission adalah koreksi SQL dari user yang menunggu review admin.
+type FeedbackSubmission struct {
+	ID                int64      `json:"id"`
+	PromptAsli        string     `json:"prompt_asli"`
+	GeneratedSQL      string     `json:"generated_sql,omitempty"`
+	SqlKoreksi        string     `json:"sql_koreksi"`
+	SubmittedBy       string     `json:"submitted_by,omitempty"`
+	Status            string     `json:"status"`
+	TestRowCount      *int       `json:"test_row_count,omitempty"`
+	TestRowsTruncated bool       `json:"test_rows_truncated,omitempty"`
+	ReviewedBy        string     `json:"reviewed_by,omitempty"`
+	ReviewNote        string     `json:"review_note,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+}
+
+const feedbackColumns = `
+	id,
+	prompt_asli,
+	COALESCE(generated_sql, ''),
+	sql_koreksi,
+	COALESCE(submitted_by, ''),
+	status,
+	test_row_count,
+	test_rows_truncated,
+	COALESCE(reviewed_by, ''),
+	COALESCE(review_note, ''),
+	created_at,
+	reviewed_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFeedback(row rowScanner) (FeedbackSubmission, error) {
+	var f FeedbackSubmission
+	var rowCount sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(&f.ID, &f.PromptAsli, &f.GeneratedSQL, &f.SqlKoreksi, &f.SubmittedBy,
+		&f.Status, &rowCount, &f.TestRowsTruncated, &f.ReviewedBy, &f.ReviewNote, &f.CreatedAt, &reviewedAt)
+	if err != nil {
+		return f, err
+	}
+	if rowCount.Valid {
+		n := int(rowCount.Int64)
+		f.TestRowCount = &n
+	}
+	if reviewedAt.Valid {
+		f.ReviewedAt = &reviewedAt.Time
+	}
+	return f, nil
+}
+
+// feedbackTestMaxRows membatasi baris yang dibaca saat menguji koreksi dari endpoint publik;
+// hasil uji hanya dipakai untuk memastikan query jalan, bukan untuk dikembalikan.
+const feedbackTestMaxRows = 100
+
+// testFeedbackSQL memvalidasi koreksi dengan checker read-only lalu mengeksekusinya di transaksi
+// read-only untuk memastikan query benar-benar jalan. truncated bernilai true jika hasil lebih
+// dari feedbackTestMaxRows baris (jumlah baris sebenarnya ≥ rowCount).
+func testFeedbackSQL(ctx context.Context, query string) (rowCount int, truncated bool, err error) {
+	if err := validateReadOnlySQL(query); err != nil {
+		return 0, false, &ValidationError{Reason: "SQL ditolak: " + err.Error()}
+	}
+	result, truncated, err := executeReadOnlyQuery(ctx, query, nil, feedbackTestMaxRows)
+	if err != nil {
+		return 0, false, &ValidationError{Reason: "SQL gagal dieksekusi: " + err.Error()}
+	}
+	return len(result.Rows), truncated, nil
+}
+
+// SubmitFeedback menyimpan koreksi sebagai submission pending setelah lolos validasi.
+func SubmitFeedback(ctx context.Context, req FeedbackRequest) (FeedbackSubmission, error) {
+	if DbInstance == nil {
+		return FeedbackSubmission{}, fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	promptAsli := strings.TrimSpace(req.PromptAsli)
+	sqlKoreksi := strings.TrimSpace(req.SqlKoreksi)
+
+	rowCount, truncated, err := testFeedbackSQL(ctx, sqlKoreksi)
+	if err != nil {
+		return FeedbackSubmission{}, err
+	}
+
+	row := DbInstance.QueryRowContext(ctx, fmt.Sprintf(`
+	INSERT INTO %s
+		(prompt_asli, generated_sql, sql_koreksi, submitted_by, test_row_count, test_rows_truncated)
+	VALUES
+		($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5, $6)
+	RETURNING %s
+	`, metaTable("feedback_submissions"), feedbackColumns),
+		promptAsli, strings.TrimSpace(req.SqlAsli), sqlKoreksi, strings.TrimSpace(req.SubmittedBy), rowCount, truncated)
+
+	f, err := scanFeedback(row)
+	if err != nil {
+		return FeedbackSubmission{}, fmt.Errorf("gagal menyimpan feedback: %w", err)
+	}
+	return f, nil
+}
+
+// ListFeedback mengembalikan submission, difilter status bila tidak kosong (terbaru dulu).
+func ListFeedback(ctx context.Context, status string, limit int) ([]FeedbackSubmission, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := DbInstance.QueryContext(ctx, fmt.Sprintf(`
+	SELECT %s
+	FROM
+		%s
+	WHERE
+		($1 = '' OR status = $1)
+	ORDER BY
+		created_at DESC
+	LIMIT $2;
+	`, feedbackColumns, metaTable("feedback_submissions")), status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca feedback: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := make([]FeedbackSubmission, 0)
+	for rows.Next() {
+		f, err := scanFeedback(rows)
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, f)
+	}
+	return submissions, rows.Err()
+}
+
+func GetFeedback(ctx context.Context, id int64) (FeedbackSubmission, error) {
+	row := DbInstance.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE id = $1", feedbackColumns, metaTable("feedback_submissions")), id)
+	f, err := scanFeedback(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, ErrFeedbackNotFound
+	}
+	return f, err
+}
+
+// reviewFeedback mengunci submission pending, menjalankan promote (bila ada) di transaksi yang
+// sama, lalu mencatat hasil review.
+func reviewFeedback(ctx context.Context, id int64, status, reviewer, note string, promote func(tx *sql.Tx, f FeedbackSubmission) error) (FeedbackSubmission, error) {
+	tx, err := DbInstance.BeginTx(ctx, nil)
+	if err != nil {
+		return FeedbackSubmission{}, err
+	}
+	defer tx.Rollback()
+
+	f, err := scanFeedback(tx.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM %s WHERE id = $1 FOR UPDATE", feedbackColumns, metaTable("feedback_submissions")), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, ErrFeedbackNotFound
+	}
+	if err != nil {
+		return f, err
+	}
+	if f.Status != FeedbackPending {
+		return f, ErrFeedbackNotPending
+	}
+
+	if promote != nil {
+		if err := promote(tx, f); err != nil {
+			return f, err
+		}
+	}
+
+	f, err = scanFeedback(tx.QueryRowContext(ctx, fmt.Sprintf(`
+	UPDATE %s
+	SET
+		status = $1,
+		reviewed_by = NULLIF($2, ''),
+		review_note = NULLIF($3, ''),
+		reviewed_at = now()
+	WHERE
+		id = $4
+	RETURNING %s
+	`, metaTable("feedback_submissions"), feedbackColumns), status, reviewer, note, id))
+	if err != nil {
+		return f, err
+	}
+	return f, tx.Commit()
+}
+
+// FeedbackApplyResult merangkum penerapan koreksi yang disetujui ke RAG dan semantic cache.
+type FeedbackApplyResult struct {
+	RAGUpserted      bool     `json:"rag_upserted"`
+	CacheInvalidated int      `json:"cache_invalidated"`
+	CacheInjected    bool     `json:"cache_injected"`
+	Warnings         []string `json:"warnings,omitempty"`
+}
+
+// ApproveFeedback memvalidasi ulang koreksi, mempromosikannya ke rag_sql_examples
+// (menggantikan contoh lama dengan prompt yang sama), lalu langsung menerapkannya ke
+// collection RAG, indeks BM25 dan semantic cache tanpa retrain penuh.
+func ApproveFeedback(ctx context.Context, id int64, reviewer, note string) (FeedbackSubmission, FeedbackApplyResult, error) {
+	var previous *SqlExample
+	var applied FeedbackApplyResult
+
+	f, err := reviewFeedback(ctx, id, FeedbackApproved, reviewer, note, func(tx *sql.Tx, f FeedbackSubmission) error {
+		if _, _, err := testFeedbackSQL(ctx, f.SqlKoreksi); err != nil {
+			return err
+		}
+
+		promptExample := fmt.Sprintf("-- Pertanyaan: \"%s\"", f.PromptAsli)
+		var exampleID int64
+		var oldSQL string
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT id, sql_example FROM %s WHERE prompt_example = $1 ORDER BY id LIMIT 1 FOR UPDATE
+		`, metaTable("rag_sql_examples")), promptExample).Scan(&exampleID, &oldSQL)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s
+				(prompt_example, sql_example, source)
+			VALUES
+				($1, $2, $3)
+			`, metaTable("rag_sql_examples")), promptExample, f.SqlKoreksi, exampleSourceFeedback)
+			if err != nil {
+				return fmt.Errorf("gagal menyimpan ke rag_sql_examples: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("gagal membaca rag_sql_examples: %w", err)
+		default:
+			previous = &SqlExample{FullContent: fmt.Sprintf("%s\n%s", promptExample, oldSQL), PromptOnly: promptExample}
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET sql_example = $1, source = $2 WHERE id = $3
+			`, metaTable("rag_sql_examples")), f.SqlKoreksi, exampleSourceFeedback, exampleID)
+			if err != nil {
+				return fmt.Errorf("gagal memperbarui rag_sql_examples: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return f, applied, err
+	}
+	log.Printf("✅ Feedback #%d disetujui, dipromosikan ke rag_sql_examples.", f.ID)
+
+	applied = applyApprovedFeedback(ctx, f, previous)
+	return f, applied, nil
+}
+
+// applyApprovedFeedback membuat koreksi langsung terpakai pada query berikutnya. Kegagalan di
+// sini tidak membatalkan approval (contoh sudah tersimpan dan akan ikut retrain berikutnya).
+func applyApprovedFeedback(ctx context.Context, f FeedbackSubmission, previous *SqlExample) FeedbackApplyResult {
+	var result FeedbackApplyResult
+	warn := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		log.Printf("PERINGATAN: Feedback #%d: %s", f.ID, msg)
+		result.Warnings = append(result.Warnings, msg)
+	}
+
+	promptExample := fmt.Sprintf("-- Pertanyaan: \"%s\"", f.PromptAsli)
+	example := SqlExample{FullContent: fmt.Sprintf("%s\n%s", promptExample, f.SqlKoreksi), PromptOnly: promptExample}
+	if err := UpsertSqlExampleToRAG(ctx, example, previous); err != nil {
+		warn("gagal menerapkan ke collection RAG (jalankan retrain): %v", err)
+	} else {
+		result.RAGUpserted = true
+	}
+
+	if err := RefreshSparseIndex(); err != nil {
+		warn("gagal membangun ulang indeks BM25: %v", err)
+	}
+
+	// Prompt di pipeline query selalu dinormalisasi ke huruf kecil sebelum di-embed
+	cachePrompt := strings.ToLower(strings.TrimSpace(f.PromptAsli))
+	vector, err := GenerateEmbedding(ctx, cachePrompt)
+	if err != nil {
+		warn("gagal embed prompt untuk cache: %v", err)
+		return result
+	}
+
+	invalidated, err := InvalidateSimilarCache(ctx, vector)
+	if err != nil {
+		warn("gagal invalidasi cache serupa: %v", err)
+	}
+	result.CacheInvalidated = invalidated
+
+	point := qdrantPoint{
+		ID:     uuid.NewString(),
+		Vector: vector,
+		Payload: map[string]interface{}{
+			"prompt_asli":    cachePrompt,
+			"sql_query":      f.SqlKoreksi,
+			"schema_version": CurrentSchemaVersion(),
+			"source":         exampleSourceFeedback,
+		},
+	}
+	if err := qdrantUpsertPoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection, []qdrantPoint{point}); err != nil {
+		warn("gagal menyimpan koreksi ke cache: %v", err)
+	} else {
+		result.CacheInjected = true
+	}
+
+	log.Printf("✅ Feedback #%d diterapkan: rag=%t, cache dihapus=%d, cache baru=%t",
+		f.ID, result.RAGUpserted, result.CacheInvalidated, result.CacheInjected)
+	return result
+}
+
+func RejectFeedback(ctx context.Context, id int64, reviewer, note string) (FeedbackSubmission, error) {
+	f, err := reviewFeedback(ctx, id, FeedbackRejected, reviewer, note, nil)
+	if err != nil {
+		return f, err
+	}
+	log.Printf("Feedback #%d ditolak oleh '%s'.", f.ID, reviewer)
+	return f, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// GenerationLogEntry adalah satu SQL yang dihasilkan pipeline beserta hasil eksekusinya.
+type GenerationLogEntry struct {
+	Prompt        string
+	GeneratedSQL  string
+	PromptVersion string
+	Provider      string
+	Model         string
+	IsCached      bool
+	ExecSuccess   *bool
+	RowCount      *int
+	Error         string
+	Latency       time.Duration
+}
+
+// PromptVersionStats merangkum generation_log per versi template prompt.
+type PromptVersionStats struct {
+	PromptVersion string   `json:"prompt_version"`
+	Total         int      `json:"total"`
+	LLMGenerated  int      `json:"llm_generated"`
+	ExecSuccess   float64  `json:"exec_success_rate"`
+	AvgLatencyMs  *float64 `json:"avg_latency_ms,omitempty"`
+}
+
+func newGenerationLogEntry(prompt string, aiResp AISqlResponse) GenerationLogEntry {
+	return GenerationLogEntry{
+		Prompt:        prompt,
+		GeneratedSQL:  aiResp.SQL,
+		PromptVersion: aiResp.PromptVersion,
+		Provider:      aiResp.Provider,
+		Model:         aiResp.Model,
+		IsCached:      aiResp.IsCached,
+	}
+}
+
+// RecordGeneration menyimpan entry ke generation_log di background; kegagalan hanya di-log.
+func RecordGeneration(entry GenerationLogEntry) {
+	if DbInstance == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := DbInstance.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s
+			(prompt, generated_sql, prompt_version, provider, model, is_cached, exec_success, row_count, error, latency_ms)
+		VALUES
+			($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''), $10)
+		`, metaTable("generation_log")),
+			entry.Prompt, entry.GeneratedSQL, entry.PromptVersion, entry.Provider, entry.Model,
+			entry.IsCached, entry.ExecSuccess, entry.RowCount, entry.Error, entry.Latency.Milliseconds())
+		if err != nil {
+			log.Printf("PERINGATAN: Gagal mencatat generation_log: %v", err)
+		}
+	}()
+}
+
+// GetPromptVersionStats menghitung tingkat keberhasilan eksekusi per versi prompt selama `days` hari terakhir.
+func GetPromptVersionStats(ctx context.Context, days int) ([]PromptVersionStats, error) {
+	if DbInstance == nil {
+		return nil, fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	rows, err := DbInstance.QueryContext(ctx, fmt.Sprintf(`
+	SELECT
+		COALESCE(prompt_version, '(tidak tercatat)'),
+		count(*),
+		count(*) FILTER (WHERE NOT is_cached),
+		COALESCE(avg(CASE WHEN exec_success THEN 1.0 ELSE 0.0 END) FILTER (WHERE exec_success IS NOT NULL), 0),
+		avg(latency_ms) FILTER (WHERE NOT is_cached)
+	FROM
+		%s
+	WHERE
+		created_at >= now() - make_interval(days => $1)
+	GROUP BY
+		1
+	ORDER BY
+		1;
+	`, metaTable("generation_log")), days)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca generation_log: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]PromptVersionStats, 0)
+	for rows.Next() {
+		var s PromptVersionStats
+		if err := rows.Scan(&s.PromptVersion, &s.Total, &s.LLMGenerated, &s.ExecSuccess, &s.AvgLatencyMs); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
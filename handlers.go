@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func respondWithError(w http.ResponseWriter, code int, message string) {
@@ -20,8 +24,19 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(response)
 }
 
+// HandleHealthCheck juga melaporkan mode degraded Qdrant dan circuit breaker tiap dependency
+// eksternal (Groq, Ollama, Gemini, Qdrant) agar gangguan terlihat dari monitoring.
 func HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	respondWithJSON(w, http.StatusOK, map[string]string{"status": "API is up and running!"})
+	status := "API is up and running!"
+	vector := CurrentVectorServiceStatus()
+	if vector.Status == "degraded" {
+		status = "degraded"
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":           status,
+		"vector_service":   vector,
+		"circuit_breakers": CircuitBreakerStatuses(),
+	})
 }
 
 func validateDangerousIntent(prompt string) error {
@@ -61,62 +76,42 @@ func HandleDynamicQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	normalizedPrompt := strings.ToLower(strings.TrimSpace(req.Prompt))
-	if normalizedPrompt == "" {
-		sendError(w, http.StatusBadRequest, "EMPTY_PROMPT", "Prompt tidak boleh kosong")
-		return
+	// Deadline seluruh request; setiap tahap mendapat porsi dari sisa waktunya (lihat stageContext).
+	// Context ikut batal jika client memutus koneksi.
+	ctx := r.Context()
+	if AppConfig.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, AppConfig.RequestTimeout)
+		defer cancel()
 	}
 
-	log.Printf("Menerima Prompt (Normalized): %s", normalizedPrompt)
-
-	if isAbsurd, err := IsAbsurdPrompt(r.Context(), normalizedPrompt); err != nil {
-		log.Printf("Error cek absurd: %v", err)
-		sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Layanan sedang bermasalah")
-		return
-	} else if isAbsurd {
-		sendAmbiguous(w, "Pertanyaan kurang jelas", []string{
-			"ada berapa orang penabung saat ini",
-			"nasabah yang jenis tabungan nya deposito",
-		})
-		return
-	}
-	if err := validateDangerousIntent(normalizedPrompt); err != nil {
-		log.Printf("SECURITY BLOCK: %v", err)
-		sendError(w, http.StatusForbidden, "DANGEROUS_INTENT", err.Error())
-		return
+	include := requestedDebugIncludes(r, req.Debug, req.Include)
+	if len(include) > 0 && !debugAllowed(r) {
+		log.Printf("Info debug diminta tanpa role yang diizinkan (%s=%q), diabaikan.", AppConfig.DebugRoleHeader, r.Header.Get(AppConfig.DebugRoleHeader))
+		include = nil
 	}
 
-	aiResp, err := GetSQL(normalizedPrompt)
+	resp, err := RunDynamicQuery(ctx, req, include)
 	if err != nil {
-		log.Printf("AI gagal generate SQL: %v", err)
-		sendError(w, http.StatusInternalServerError, "AI_GENERATION_FAILED", "Gagal menghasilkan query SQL")
-		return
-	}
-
-	if aiResp.IsAmbiguous {
-		sendAmbiguous(w, "Maaf, pertanyaan Anda kurang jelas atau tidak cukup spesifik", aiResp.Suggestions)
-		return
-	}
-	if strings.TrimSpace(aiResp.SQL) == "" {
-		sendError(w, http.StatusUnprocessableEntity, "EMPTY_SQL", "AI tidak menghasilkan query SQL yang valid")
-		return
-	}
-
-	log.Printf("SQL yang akan dieksekusi: %s", aiResp.SQL)
-	data, execErr := ExecuteDynamicQuery(aiResp.SQL, nil)
-	if execErr != nil {
-		log.Printf("GAGAL EKSEKUSI QUERY: %v | SQL: %s", execErr, aiResp.SQL)
-		sendError(w, http.StatusUnprocessableEntity, "QUERY_EXECUTION_FAILED",
-			"Query tidak dapat dieksekusi. Mungkin syntax salah atau melanggar aturan database",
-			execErr.Error())
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			queryErr = &QueryError{StatusCode: http.StatusInternalServerError, Code: "INTERNAL_ERROR", Message: "Layanan sedang bermasalah"}
+		}
+		if handleRequestContextError(w, ctx, queryErr.Stage) {
+			return
+		}
+		sendError(w, queryErr.StatusCode, queryErr.Code, queryErr.Message, queryErr.Detail)
 		return
 	}
 
-	if !aiResp.IsCached {
-		go SaveToCache(aiResp.PromptAsli, aiResp.Vector, aiResp.SQL)
+	switch resp.Status {
+	case queryStatusAmbiguous:
+		sendAmbiguous(w, resp.Message, resp.Suggestions, resp.Questions)
+	case queryStatusConfirmationRequired:
+		sendConfirmationRequired(w, resp)
+	default:
+		sendSuccessResponse(w, resp)
 	}
-
-	sendSuccess(w, data)
 }
 
 func HandleFeedbackKoreksi(w http.ResponseWriter, r *http.Request) {
@@ -148,18 +143,338 @@ func HandleFeedbackKoreksi(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Menerima Feedback Koreksi Baru. Prompt: %s", promptAsli)
 
-	if err := AddSqlExample(promptAsli, sqlKoreksi); err != nil {
+	submission, err := SubmitFeedback(r.Context(), req)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		log.Printf("Feedback ditolak validasi: %v", err)
+		respondWithError(w, http.StatusUnprocessableEntity, validationErr.Error())
+		return
+	}
+	if err != nil {
 		log.Printf("ERROR: Gagal menyimpan feedback: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Gagal menyimpan feedback ke database")
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, map[string]string{
-		"status":  "sukses",
-		"message": "Feedback koreksi berhasil disimpan. Silakan 'retrain' untuk menerapkan.",
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status":   "pending",
+		"message":  "Feedback koreksi diterima dan menunggu review admin.",
+		"feedback": submission,
 	})
 }
 
+func HandleAdminFeedbackList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != FeedbackPending && status != FeedbackApproved && status != FeedbackRejected {
+		respondWithError(w, http.StatusBadRequest, "Parameter 'status' harus pending, approved, atau rejected")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	submissions, err := ListFeedback(r.Context(), status, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, submissions)
+}
+
+func HandleAdminFeedbackGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID feedback tidak valid")
+		return
+	}
+
+	submission, err := GetFeedback(r.Context(), id)
+	if errors.Is(err, ErrFeedbackNotFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, submission)
+}
+
+// HandleAdminFeedbackReview menangani POST /admin/feedback/{id}/approve dan /reject.
+func HandleAdminFeedbackReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID feedback tidak valid")
+		return
+	}
+
+	var req FeedbackReviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Request body JSON tidak valid")
+			return
+		}
+	}
+
+	var submission FeedbackSubmission
+	var applied *FeedbackApplyResult
+	switch r.PathValue("action") {
+	case "approve":
+		log.Printf("ADMIN: Menyetujui feedback #%d", id)
+		var result FeedbackApplyResult
+		submission, result, err = ApproveFeedback(r.Context(), id, req.ReviewedBy, req.Note)
+		applied = &result
+	case "reject":
+		log.Printf("ADMIN: Menolak feedback #%d", id)
+		submission, err = RejectFeedback(r.Context(), id, req.ReviewedBy, req.Note)
+	default:
+		respondWithError(w, http.StatusNotFound, "Aksi review harus approve atau reject")
+		return
+	}
+
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, ErrFeedbackNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrFeedbackNotPending):
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":    err.Error(),
+			"feedback": submission,
+		})
+	case errors.As(err, &validationErr):
+		respondWithError(w, http.StatusUnprocessableEntity, validationErr.Error())
+	case err != nil:
+		log.Printf("ERROR: Gagal review feedback #%d: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Gagal memproses review feedback")
+	case applied != nil:
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"feedback": submission,
+			"applied":  applied,
+		})
+	default:
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"feedback": submission,
+		})
+	}
+}
+
+// respondDictionaryError memetakan error layanan kamus ke status HTTP.
+func respondDictionaryError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondWithError(w, http.StatusUnprocessableEntity, validationErr.Error())
+	case errors.Is(err, ErrDictionaryNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrDictionaryConflict), errors.Is(err, ErrDictionaryDuplicate):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("ERROR: Operasi kamus gagal: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Gagal memproses kamus istilah")
+	}
+}
+
+// HandleAdminDictionary menangani GET (daftar) dan POST (tambah) /admin/dictionary.
+func HandleAdminDictionary(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		items, err := ListDictionary(r.Context(), r.URL.Query().Get("include_inactive") == "true")
+		if err != nil {
+			respondDictionaryError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, items)
+	case http.MethodPost:
+		var in DictionaryInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Request body JSON tidak valid")
+			return
+		}
+		item, err := CreateDictionaryItem(r.Context(), in)
+		if err != nil {
+			respondDictionaryError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, item)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+	}
+}
+
+// HandleAdminDictionaryItem menangani GET, PUT dan DELETE /admin/dictionary/{id}.
+func HandleAdminDictionaryItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID istilah tidak valid")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		item, err := GetDictionaryItem(r.Context(), id)
+		if err != nil {
+			respondDictionaryError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, item)
+	case http.MethodPut:
+		var in DictionaryInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Request body JSON tidak valid")
+			return
+		}
+		item, err := UpdateDictionaryItem(r.Context(), id, in)
+		if err != nil {
+			respondDictionaryError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, item)
+	case http.MethodDelete:
+		version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+		item, err := DeleteDictionaryItem(r.Context(), id, version, r.URL.Query().Get("updated_by"))
+		if err != nil {
+			respondDictionaryError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, item)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+	}
+}
+
+func HandleAdminDictionaryHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID istilah tidak valid")
+		return
+	}
+
+	history, err := DictionaryHistory(r.Context(), id)
+	if err != nil {
+		respondDictionaryError(w, err)
+		return
+	}
+	if len(history) == 0 {
+		respondDictionaryError(w, ErrDictionaryNotFound)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+func respondAbsurdKeywordError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondWithError(w, http.StatusUnprocessableEntity, validationErr.Error())
+	case errors.Is(err, ErrAbsurdKeywordNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("ERROR: Operasi absurd_keywords gagal: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Gagal memproses absurd_keywords")
+	}
+}
+
+// HandleAdminAbsurdKeywords menangani GET (daftar) dan POST (tambah) /admin/absurd-keywords.
+func HandleAdminAbsurdKeywords(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		keywords, err := ListAbsurdKeywords(r.Context(), r.URL.Query().Get("active") == "true")
+		if err != nil {
+			respondAbsurdKeywordError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, keywords)
+	case http.MethodPost:
+		var in AbsurdKeywordInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Request body JSON tidak valid")
+			return
+		}
+		keyword, err := CreateAbsurdKeyword(r.Context(), in)
+		if err != nil {
+			respondAbsurdKeywordError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, keyword)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+	}
+}
+
+// HandleAdminAbsurdKeyword menangani PUT dan DELETE /admin/absurd-keywords/{id}.
+func HandleAdminAbsurdKeyword(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID keyword tidak valid")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var in AbsurdKeywordInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Request body JSON tidak valid")
+			return
+		}
+		keyword, err := UpdateAbsurdKeyword(r.Context(), id, in)
+		if err != nil {
+			respondAbsurdKeywordError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, keyword)
+	case http.MethodDelete:
+		if err := DeleteAbsurdKeyword(r.Context(), id); err != nil {
+			respondAbsurdKeywordError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{
+			"status":  "deleted",
+			"message": fmt.Sprintf("Keyword ID %d berhasil dihapus.", id),
+		})
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+	}
+}
+
+// HandleAdminAbsurdKeywordsTest menjalankan ClassifyPrompt untuk satu prompt tanpa memanggil LLM.
+func HandleAdminAbsurdKeywordsTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	var req PromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Prompt) == "" {
+		respondWithError(w, http.StatusBadRequest, "Field 'prompt' wajib diisi")
+		return
+	}
+
+	verdict, err := ClassifyPrompt(r.Context(), strings.ToLower(strings.TrimSpace(req.Prompt)))
+	if err != nil {
+		respondAbsurdKeywordError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, verdict)
+}
+
 func HandleAdminRetrain(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
@@ -168,13 +483,163 @@ func HandleAdminRetrain(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("ADMIN: Menerima permintaan /admin/retrain...")
 
-	go func() {
-		log.Println("ADMIN: training RAG (Embedding) dimulai")
-		mainTrain()
-	}()
+	// Default inkremental; ?full=true memaksa embed ulang semua item
+	opts := TrainOptions{Incremental: r.URL.Query().Get("full") != "true"}
 
-	respondWithJSON(w, http.StatusAccepted, map[string]string{
-		"message": "Proses retraining RAG telah selesai",
+	job, err := trainJobs.Start(opts)
+	if errors.Is(err, ErrTrainJobRunning) {
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"message": "Proses retraining lain masih berjalan",
+			"job":     job,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Proses retraining RAG dimulai",
+		"job_id":  job.ID,
+		"job":     job,
+	})
+}
+
+func HandleAdminRetrainJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		job, ok := trainJobs.Get(id)
+		if !ok {
+			respondWithError(w, http.StatusNotFound, "Job retraining tidak ditemukan")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, job)
+	case http.MethodDelete:
+		job, ok := trainJobs.Cancel(id)
+		if !ok {
+			respondWithError(w, http.StatusNotFound, "Job retraining tidak ditemukan")
+			return
+		}
+		log.Printf("ADMIN: Permintaan pembatalan job retraining %s", id)
+		respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message": "Permintaan pembatalan dikirim",
+			"job":     job,
+		})
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+	}
+}
+
+func schemaSnapshotSummary(snap *SchemaSnapshot) map[string]interface{} {
+	tableNames := make([]string, 0, len(snap.Tables))
+	for _, t := range snap.Tables {
+		tableNames = append(tableNames, t.Name)
+	}
+	return map[string]interface{}{
+		"version":      snap.Version,
+		"refreshed_at": snap.RefreshedAt,
+		"age_seconds":  int(time.Since(snap.RefreshedAt).Seconds()),
+		"table_count":  len(snap.Tables),
+		"tables":       tableNames,
+		"ddl":          snap.DDLs,
+		"dictionary":   len(snap.Dictionary),
+	}
+}
+
+func HandleAdminSchemaSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	snap, err := CurrentSchemaSnapshot(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Gagal memuat schema snapshot: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, schemaSnapshotSummary(snap))
+}
+
+func HandleAdminSchemaRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	log.Println("ADMIN: Menerima permintaan refresh schema snapshot...")
+	previousVersion := CurrentSchemaVersion()
+
+	snap, err := RefreshSchemaSnapshot(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Gagal refresh schema snapshot: "+err.Error())
+		return
+	}
+
+	summary := schemaSnapshotSummary(snap)
+	summary["previous_version"] = previousVersion
+	summary["changed"] = previousVersion != snap.Version
+	respondWithJSON(w, http.StatusOK, summary)
+}
+
+// HandleAdminPromptTemplates menampilkan versi template prompt, rollout aktif dan
+// statistik eksekusi per versi dari generation_log (?days=N, default 30).
+func HandleAdminPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Parameter days tidak valid")
+			return
+		}
+		days = n
+	}
+
+	templates, rollout, err := ListPromptTemplates()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Gagal memuat template prompt: "+err.Error())
+		return
+	}
+	stats, err := GetPromptVersionStats(r.Context(), days)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"default_version": AppConfig.PromptDefaultVersion,
+		"rollout":         rollout,
+		"templates":       templates,
+		"stats_days":      days,
+		"stats":           stats,
+	})
+}
+
+// HandleAdminPromptTemplatesReload memuat ulang template dari PROMPT_TEMPLATES_DIR tanpa restart.
+func HandleAdminPromptTemplatesReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+
+	log.Println("ADMIN: Memuat ulang template prompt...")
+	if _, err := ReloadPromptTemplates(); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Gagal memuat template prompt: "+err.Error())
+		return
+	}
+	templates, rollout, err := ListPromptTemplates()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"default_version": AppConfig.PromptDefaultVersion,
+		"rollout":         rollout,
+		"templates":       templates,
 	})
 }
 
@@ -193,7 +658,7 @@ func HandleAdminListQdrant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := GetAllQdrantPoints(collectionName, 1000)
+	data, err := GetAllQdrantPoints(r.Context(), collectionName, 1000)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -260,7 +725,7 @@ func HandleAdminCacheCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := ManualInjectCache(req.Prompt, req.SQL); err != nil {
+	if err := ManualInjectCache(r.Context(), req.Prompt, req.SQL); err != nil {
 		log.Printf("Gagal inject cache: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Gagal menyimpan ke cache: "+err.Error())
 		return
@@ -313,7 +778,7 @@ func HandleAdminQdrantUpdate(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := UpdateQdrantPoint(req.Collection, req.ID, req.Prompt, req.SQL); err != nil {
+	if err := UpdateQdrantPoint(r.Context(), req.Collection, req.ID, req.Prompt, req.SQL); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status satu komponen dan status readiness keseluruhan.
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+	healthDown     = "down"
+	healthSkipped  = "skipped"
+
+	readinessReady    = "ready"
+	readinessDegraded = "degraded"
+	readinessNotReady = "not_ready"
+)
+
+var processStartedAt = time.Now()
+
+// ComponentHealth adalah hasil pemeriksaan satu dependency.
+type ComponentHealth struct {
+	Name string `json:"name"`
+	// Status: ok, degraded, down atau skipped (tidak dikonfigurasi)
+	Status string `json:"status"`
+	// Critical: komponen yang wajib ok agar layanan dianggap ready
+	Critical  bool                   `json:"critical"`
+	LatencyMs float64                `json:"latency_ms"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// ReadinessReport adalah response /health/ready.
+type ReadinessReport struct {
+	Status          string                 `json:"status"`
+	CheckedAt       time.Time              `json:"checked_at"`
+	DurationMs      float64                `json:"duration_ms"`
+	Components      []ComponentHealth      `json:"components"`
+	CircuitBreakers []CircuitBreakerStatus `json:"circuit_breakers"`
+}
+
+type healthCheck struct {
+	name     string
+	critical bool
+	run      func(ctx context.Context, c *ComponentHealth)
+}
+
+// healthChecks menyusun pemeriksaan readiness. Komponen LLM tidak kritis satu per satu;
+// RunReadinessChecks mensyaratkan minimal satu provider LLM yang ok.
+func healthChecks() []healthCheck {
+	checks := []healthCheck{
+		{name: "postgres", critical: true, run: checkPostgres},
+		{name: "embedding", critical: true, run: checkEmbedding},
+		{name: "schema_snapshot", critical: true, run: checkSchemaSnapshot},
+		{name: "qdrant_rag", run: func(ctx context.Context, c *ComponentHealth) {
+			checkQdrantCollection(ctx, c, AppConfig.QdrantCollectionName)
+		}},
+		{name: "qdrant_cache", run: func(ctx context.Context, c *ComponentHealth) {
+			checkQdrantCollection(ctx, c, AppConfig.QdrantCacheCollection)
+		}},
+		{name: "llm_groq", run: checkGroq},
+		{name: "llm_ollama", run: checkOllama},
+	}
+	return checks
+}
+
+// RunReadinessChecks menjalankan semua pemeriksaan secara paralel, masing-masing dibatasi
+// HEALTH_CHECK_TIMEOUT_SECONDS.
+func RunReadinessChecks(ctx context.Context) ReadinessReport {
+	start := time.Now()
+	checks := healthChecks()
+	components := make([]ComponentHealth, len(checks))
+
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		components[i] = ComponentHealth{Name: hc.name, Critical: hc.critical, Status: healthOK}
+		wg.Add(1)
+		go func(c *ComponentHealth, run func(context.Context, *ComponentHealth)) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithCancel(ctx)
+			if AppConfig.HealthCheckTimeout > 0 {
+				checkCtx, cancel = context.WithTimeout(ctx, AppConfig.HealthCheckTimeout)
+			}
+			defer cancel()
+			checkStart := time.Now()
+			run(checkCtx, c)
+			c.LatencyMs = math.Round(float64(time.Since(checkStart).Microseconds())/100) / 10
+		}(&components[i], hc.run)
+	}
+	wg.Wait()
+
+	report := ReadinessReport{
+		Status:          aggregateReadiness(components),
+		CheckedAt:       start,
+		Components:      components,
+		CircuitBreakers: CircuitBreakerStatuses(),
+	}
+	report.DurationMs = math.Round(float64(time.Since(start).Microseconds())/100) / 10
+	return report
+}
+
+// aggregateReadiness menentukan status keseluruhan: not_ready jika komponen kritis down atau
+// tidak ada provider LLM yang ok, degraded jika ada komponen lain yang down/degraded.
+func aggregateReadiness(components []ComponentHealth) string {
+	status := readinessReady
+	llmOK := false
+	for _, c := range components {
+		switch {
+		case c.Critical && c.Status == healthDown:
+			status = readinessNotReady
+		case c.Status == healthDown || c.Status == healthDegraded:
+			if status == readinessReady {
+				status = readinessDegraded
+			}
+		}
+		if strings.HasPrefix(c.Name, "llm_") && c.Status == healthOK {
+			llmOK = true
+		}
+	}
+	if !llmOK {
+		status = readinessNotReady
+	}
+	return status
+}
+
+func (c *ComponentHealth) fail(status string, format string, args ...interface{}) {
+	c.Status = status
+	c.Message = fmt.Sprintf(format, args...)
+}
+
+func (c *ComponentHealth) detail(key string, value interface{}) {
+	if c.Details == nil {
+		c.Details = make(map[string]interface{})
+	}
+	c.Details[key] = value
+}
+
+func checkPostgres(ctx context.Context, c *ComponentHealth) {
+	if DbInstance == nil {
+		c.fail(healthDown, "koneksi database belum dibuat")
+		return
+	}
+	if err := DbInstance.PingContext(ctx); err != nil {
+		c.fail(healthDown, "ping gagal: %v", err)
+		return
+	}
+	stats := DbInstance.Stats()
+	c.detail("open_connections", stats.OpenConnections)
+	c.detail("in_use", stats.InUse)
+}
+
+// checkEmbedding memanggil endpoint info model embedding (tanpa membuat embedding).
+func checkEmbedding(ctx context.Context, c *ComponentHealth) {
+	c.detail("model", AppConfig.EmbeddingModel)
+	if geminiEmbedder == nil {
+		c.fail(healthDown, "service embedding belum diinisialisasi")
+		return
+	}
+	if _, err := geminiEmbedder.Info(ctx); err != nil {
+		c.fail(healthDown, "model embedding tidak bisa dijangkau: %v", err)
+	}
+}
+
+// checkSchemaSnapshot memastikan snapshot skema sudah dimuat dan tidak lebih tua dari
+// SCHEMA_SNAPSHOT_MAX_AGE_SECONDS (default 3× SCHEMA_REFRESH_INTERVAL_SECONDS).
+func checkSchemaSnapshot(ctx context.Context, c *ComponentHealth) {
+	snap := LoadedSchemaSnapshot()
+	if snap == nil {
+		c.fail(healthDown, "snapshot skema belum dimuat")
+		return
+	}
+	age := time.Since(snap.RefreshedAt)
+	maxAge := AppConfig.SchemaSnapshotMaxAge
+	if maxAge <= 0 {
+		maxAge = 3 * AppConfig.SchemaRefreshInterval
+	}
+	c.detail("version", snap.Version)
+	c.detail("tables", len(snap.Tables))
+	c.detail("refreshed_at", snap.RefreshedAt)
+	c.detail("age_seconds", int(age.Seconds()))
+	if maxAge > 0 && age > maxAge {
+		c.fail(healthDegraded, "snapshot skema berumur %s (batas %s)", age.Round(time.Second), maxAge)
+	}
+}
+
+// checkQdrantCollection memastikan collection (atau alias) ada dan dimensi vektornya sama
+// dengan EMBEDDING_VECTOR_SIZE.
+func checkQdrantCollection(ctx context.Context, c *ComponentHealth, name string) {
+	c.detail("collection", name)
+	if VectorServiceDegraded() {
+		c.detail("degraded_mode", true)
+	}
+	if target, err := qdrantResolveAlias(ctx, AppConfig.QdrantURL, name); err == nil && target != "" {
+		c.detail("alias_of", target)
+	}
+
+	info, err := qdrantGetCollectionInfo(ctx, AppConfig.QdrantURL, name)
+	if err != nil {
+		c.fail(healthDown, "%v", err)
+		return
+	}
+	if !info.Exists {
+		c.fail(healthDown, "collection '%s' tidak ditemukan", name)
+		return
+	}
+	c.detail("vector_size", info.VectorSize)
+	c.detail("points_count", info.PointsCount)
+	if info.VectorSize != AppConfig.EmbeddingVectorSize {
+		c.fail(healthDown, "dimensi vektor %d tidak sama dengan EMBEDDING_VECTOR_SIZE %d", info.VectorSize, AppConfig.EmbeddingVectorSize)
+	}
+}
+
+// groqModelsURL menurunkan endpoint daftar model dari GROQ_API_URL (…/chat/completions → …/models).
+func groqModelsURL() string {
+	if AppConfig.GroqModelsURL != "" {
+		return AppConfig.GroqModelsURL
+	}
+	return strings.TrimSuffix(strings.TrimRight(AppConfig.GroqAPIURL, "/"), "/chat/completions") + "/models"
+}
+
+// checkGroq memanggil daftar model Groq (murah, tanpa token) dan memastikan GROQ_MODEL tersedia.
+func checkGroq(ctx context.Context, c *ComponentHealth) {
+	if AppConfig.GroqAPIKey == "" {
+		c.fail(healthSkipped, "GROQ_API_KEY tidak dikonfigurasi")
+		return
+	}
+	c.detail("model", AppConfig.GroqModel)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+AppConfig.GroqAPIKey)
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := getHealthJSON(ctx, depGroq, groqModelsURL(), header, &models); err != nil {
+		c.fail(healthDown, "%v", err)
+		return
+	}
+	ids := make([]string, 0, len(models.Data))
+	for _, m := range models.Data {
+		ids = append(ids, m.ID)
+	}
+	checkModelListed(c, AppConfig.GroqModel, ids)
+}
+
+// checkOllama memanggil /api/tags Ollama dan memastikan OLLAMA_MODEL sudah di-pull.
+func checkOllama(ctx context.Context, c *ComponentHealth) {
+	if AppConfig.OllamaURL == "" {
+		c.fail(healthSkipped, "OLLAMA_URL tidak dikonfigurasi")
+		return
+	}
+	c.detail("model", AppConfig.OllamaModel)
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := getHealthJSON(ctx, depOllama, strings.TrimRight(AppConfig.OllamaURL, "/")+"/api/tags", nil, &tags); err != nil {
+		c.fail(healthDown, "%v", err)
+		return
+	}
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	checkModelListed(c, AppConfig.OllamaModel, names)
+}
+
+// checkModelListed menandai komponen degraded jika model yang dikonfigurasi tidak ada di daftar.
+// Nama Ollama tanpa tag dianggap sama dengan ":latest".
+func checkModelListed(c *ComponentHealth, model string, available []string) {
+	c.detail("models_available", len(available))
+	if model == "" {
+		return
+	}
+	for _, name := range available {
+		if name == model || name == model+":latest" {
+			return
+		}
+	}
+	c.fail(healthDegraded, "model '%s' tidak ada di daftar model provider", model)
+}
+
+// getHealthJSON melakukan satu GET (tanpa retry & circuit breaker) dan men-decode response JSON;
+// status non-200 menjadi error.
+func getHealthJSON(ctx context.Context, dep, url string, header http.Header, out interface{}) error {
+	resp, body, err := httpClient(dep).DoJSON(ctx, http.MethodGet, url, nil, header, withProbe())
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, truncateForMessage(string(body), 200))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("response tidak valid: %w", err)
+	}
+	return nil
+}
+
+func truncateForMessage(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// HandleHealthLive hanya menandakan proses hidup dan bisa melayani HTTP (liveness probe);
+// dependency tidak diperiksa agar gangguan eksternal tidak memicu restart.
+func HandleHealthLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "alive",
+		"started_at":     processStartedAt,
+		"uptime_seconds": int(time.Since(processStartedAt).Seconds()),
+	})
+}
+
+// HandleHealthReady memeriksa semua dependency (readiness probe). 503 jika komponen kritis down
+// atau tidak ada provider LLM yang bisa dijangkau; 200 untuk ready maupun degraded.
+func HandleHealthReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondWithError(w, http.StatusMethodNotAllowed, "Metode tidak diizinkan")
+		return
+	}
+	report := RunReadinessChecks(r.Context())
+	code := http.StatusOK
+	if report.Status == readinessNotReady {
+		code = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, code, report)
+}
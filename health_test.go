@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestAggregateReadiness(t *testing.T) {
+	comp := func(name, status string, critical bool) ComponentHealth {
+		return ComponentHealth{Name: name, Status: status, Critical: critical}
+	}
+
+	tests := []struct {
+		name       string
+		components []ComponentHealth
+		want       string
+	}{
+		{"semua ok", []ComponentHealth{
+			comp("postgres", healthOK, true), comp("qdrant_rag", healthOK, false), comp("llm_groq", healthOK, false),
+		}, readinessReady},
+		{"komponen kritis down", []ComponentHealth{
+			comp("postgres", healthDown, true), comp("llm_groq", healthOK, false),
+		}, readinessNotReady},
+		{"komponen non-kritis down", []ComponentHealth{
+			comp("postgres", healthOK, true), comp("qdrant_cache", healthDown, false), comp("llm_groq", healthOK, false),
+		}, readinessDegraded},
+		{"komponen kritis degraded", []ComponentHealth{
+			comp("schema_snapshot", healthDegraded, true), comp("llm_groq", healthOK, false),
+		}, readinessDegraded},
+		{"kritis down tidak tertimpa degraded sesudahnya", []ComponentHealth{
+			comp("postgres", healthDown, true), comp("qdrant_rag", healthDegraded, false), comp("llm_groq", healthOK, false),
+		}, readinessNotReady},
+		{"satu provider LLM cukup", []ComponentHealth{
+			comp("postgres", healthOK, true), comp("llm_groq", healthDown, false), comp("llm_ollama", healthOK, false),
+		}, readinessDegraded},
+		{"semua provider LLM down", []ComponentHealth{
+			comp("postgres", healthOK, true), comp("llm_groq", healthDown, false), comp("llm_ollama", healthDown, false),
+		}, readinessNotReady},
+		{"provider LLM tidak dikonfigurasi", []ComponentHealth{
+			comp("postgres", healthOK, true), comp("llm_groq", healthOK, false), comp("llm_ollama", healthSkipped, false),
+		}, readinessReady},
+		{"tanpa provider LLM ok", []ComponentHealth{
+			comp("postgres", healthOK, true), comp("llm_groq", healthSkipped, false),
+		}, readinessNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aggregateReadiness(tt.components); got != tt.want {
+				t.Errorf("aggregateReadiness = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,28 +5,40 @@ import (
 	"net/http"
 )
 
-func sendSuccess(w http.ResponseWriter, data interface{}) {
-	resp := QueryResponse{
-		Status:  "success",
-		Message: "Query berhasil dieksekusi",
-		Data:    data,
+// sendSuccessResponse mengirim status "success" beserta field tambahan (confidence, voting).
+func sendSuccessResponse(w http.ResponseWriter, resp QueryResponse) {
+	resp.Status = "success"
+	if resp.Message == "" {
+		resp.Message = "Query berhasil dieksekusi"
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
-func sendAmbiguous(w http.ResponseWriter, message string, suggestions []string) {
+// sendAmbiguous mengirim status "ambiguous" beserta saran rephrasing dan (opsional)
+// pertanyaan klarifikasi yang perlu dijawab user.
+func sendAmbiguous(w http.ResponseWriter, message string, suggestions []string, questions []string) {
 	resp := QueryResponse{
 		Status:      "ambiguous",
 		Message:     message,
 		Suggestions: suggestions,
+		Questions:   questions,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
+// sendConfirmationRequired mengirim status "confirmation_required": SQL belum dieksekusi karena
+// confidence di bawah CONFIDENCE_THRESHOLD; client bisa mengirim ulang dengan "confirm": true.
+func sendConfirmationRequired(w http.ResponseWriter, resp QueryResponse) {
+	resp.Status = "confirmation_required"
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
 func sendError(w http.ResponseWriter, statusCode int, code, message string, details ...string) {
 	resp := QueryResponse{
 		Status:      "error",
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ragCandidate adalah satu contoh SQL hasil retrieval (dense, sparse, atau gabungan).
+type ragCandidate struct {
+	ID         string // point ID di collection RAG
+	Content    string
+	DenseScore float32
+	// SparseScore adalah skor BM25 yang dinormalisasi ke skor maksimum query (0–1)
+	SparseScore float64
+	FusedScore  float64
+}
+
+// Stopword sederhana agar BM25 fokus ke nama tabel, kolom, dan nomor rekening/CIF.
+var sparseStopwords = map[string]bool{
+	"yang": true, "dan": true, "di": true, "ke": true, "dari": true, "ada": true,
+	"apa": true, "saja": true, "dengan": true, "untuk": true, "semua": true,
+	"tampilkan": true, "siapa": true, "berapa": true, "the": true, "pertanyaan": true,
+	"select": true, "from": true, "where": true, "as": true, "on": true, "by": true,
+	"and": true, "or": true, "join": true,
+}
+
+func tokenizeForSparse(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len(f) < 2 || sparseStopwords[f] {
+			continue
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// bm25Index adalah indeks keyword lokal (Okapi BM25) atas isi rag_sql_examples.
+type bm25Index struct {
+	docs      []string
+	ids       []string // point ID RAG per dokumen (opsional)
+	termFreqs []map[string]int
+	docLens   []int
+	docFreq   map[string]int
+	avgDocLen float64
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+func newBM25Index(docs []string) *bm25Index {
+	idx := &bm25Index{
+		docs:      docs,
+		termFreqs: make([]map[string]int, len(docs)),
+		docLens:   make([]int, len(docs)),
+		docFreq:   make(map[string]int),
+	}
+
+	totalLen := 0
+	for i, doc := range docs {
+		tf := make(map[string]int)
+		tokens := tokenizeForSparse(doc)
+		for _, t := range tokens {
+			tf[t]++
+		}
+		for t := range tf {
+			idx.docFreq[t]++
+		}
+		idx.termFreqs[i] = tf
+		idx.docLens[i] = len(tokens)
+		totalLen += len(tokens)
+	}
+	if len(docs) > 0 {
+		idx.avgDocLen = float64(totalLen) / float64(len(docs))
+	}
+	return idx
+}
+
+// Search mengembalikan dokumen dengan skor BM25 tertinggi. Skor mentah BM25 tidak punya skala
+// tetap (naik seiring ukuran korpus dan panjang query), jadi skor dibagi skor maksimum yang
+// mungkin dicapai query ini — Σ idf × (k1+1) atas term query yang ada di korpus — sehingga
+// SparseScore selalu di rentang 0–1 dan bisa dibandingkan dengan RAG_SPARSE_MIN_SCORE.
+func (idx *bm25Index) Search(query string, limit int) []ragCandidate {
+	if idx == nil || len(idx.docs) == 0 {
+		return nil
+	}
+
+	queryTerms := tokenizeForSparse(query)
+	n := float64(len(idx.docs))
+
+	idfs := make(map[string]float64, len(queryTerms))
+	var maxScore float64
+	for _, term := range queryTerms {
+		df := float64(idx.docFreq[term])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		idfs[term] = idf
+		maxScore += idf * (bm25K1 + 1)
+	}
+	if maxScore == 0 {
+		return nil
+	}
+
+	var results []ragCandidate
+	for i, tf := range idx.termFreqs {
+		var score float64
+		for _, term := range queryTerms {
+			freq, ok := tf[term]
+			if !ok {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(idx.docLens[i])/idx.avgDocLen
+			score += idfs[term] * (float64(freq) * (bm25K1 + 1)) / (float64(freq) + bm25K1*norm)
+		}
+		if score > 0 {
+			c := ragCandidate{Content: idx.docs[i], SparseScore: score / maxScore}
+			if i < len(idx.ids) {
+				c.ID = idx.ids[i]
+			}
+			results = append(results, c)
+		}
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].SparseScore > results[b].SparseScore
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+var (
+	sparseIndexMu sync.RWMutex
+	sparseIndex   *bm25Index
+)
+
+// RefreshSparseIndex membangun ulang indeks BM25 dari tabel rag_sql_examples.
+func RefreshSparseIndex() error {
+	examples, err := GetDynamicSqlExamples()
+	if err != nil {
+		return err
+	}
+
+	docs := make([]string, 0, len(examples))
+	ids := make([]string, 0, len(examples))
+	for _, ex := range examples {
+		docs = append(docs, ex.FullContent)
+		ids = append(ids, sqlExampleRagItem(ex).pointID())
+	}
+
+	idx := newBM25Index(docs)
+	idx.ids = ids
+
+	sparseIndexMu.Lock()
+	sparseIndex = idx
+	sparseIndexMu.Unlock()
+
+	log.Printf("✅ Indeks BM25 (sparse) dibangun ulang: %d dokumen.", len(docs))
+	return nil
+}
+
+func searchSparseExamples(query string, limit int) []ragCandidate {
+	sparseIndexMu.RLock()
+	idx := sparseIndex
+	sparseIndexMu.RUnlock()
+
+	if idx == nil {
+		if err := RefreshSparseIndex(); err != nil {
+			log.Printf("PERINGATAN: Gagal membangun indeks BM25: %v", err)
+			return nil
+		}
+		sparseIndexMu.RLock()
+		idx = sparseIndex
+		sparseIndexMu.RUnlock()
+	}
+
+	return idx.Search(query, limit)
+}
+
+// fuseRRF menggabungkan ranking dense & sparse dengan Reciprocal Rank Fusion berbobot:
+// score = w_dense/(k+rank_dense) + w_sparse/(k+rank_sparse).
+func fuseRRF(dense, sparse []ragCandidate, denseWeight, sparseWeight float64, k int, limit int) []ragCandidate {
+	byContent := make(map[string]*ragCandidate)
+	var order []string
+
+	add := func(list []ragCandidate, weight float64, isDense bool) {
+		for rank, c := range list {
+			existing, ok := byContent[c.Content]
+			if !ok {
+				copied := ragCandidate{ID: c.ID, Content: c.Content}
+				existing = &copied
+				byContent[c.Content] = existing
+				order = append(order, c.Content)
+			}
+			if isDense {
+				existing.DenseScore = c.DenseScore
+			} else {
+				existing.SparseScore = c.SparseScore
+			}
+			existing.FusedScore += weight / float64(k+rank+1)
+		}
+	}
+	add(dense, denseWeight, true)
+	add(sparse, sparseWeight, false)
+
+	fused := make([]ragCandidate, 0, len(order))
+	for _, content := range order {
+		fused = append(fused, *byContent[content])
+	}
+	sort.SliceStable(fused, func(a, b int) bool {
+		return fused[a].FusedScore > fused[b].FusedScore
+	})
+	if limit > 0 && len(fused) > limit {
+		fused = fused[:limit]
+	}
+	return fused
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeForSparse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"stopword dan huruf tunggal dibuang", "Tampilkan saldo dari nasabah A", []string{"saldo", "nasabah"}},
+		{"underscore dan angka dipertahankan", "SELECT no_rekening FROM tabungan WHERE cif = 12345", []string{"no_rekening", "tabungan", "cif", "12345"}},
+		{"tanda baca memisahkan token", "kredit,macet;(kolektibilitas)", []string{"kredit", "macet", "kolektibilitas"}},
+		{"kosong", "  ", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizeForSparse(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeForSparse(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25Search(t *testing.T) {
+	docs := []string{
+		"saldo tabungan nasabah per cabang",
+		"daftar kredit macet per kolektibilitas",
+		"jumlah nasabah baru per bulan",
+		"saldo deposito jatuh tempo",
+	}
+	idx := newBM25Index(docs)
+	idx.ids = []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		name    string
+		query   string
+		limit   int
+		wantIDs []string
+	}{
+		{"term langka menang", "kredit macet", 0, []string{"b"}},
+		{"dokumen dengan lebih banyak term di atas", "saldo tabungan", 0, []string{"a", "d"}},
+		{"limit memotong hasil", "saldo nasabah", 1, []string{"a"}},
+		{"tanpa term yang cocok", "valas", 0, nil},
+		{"hanya stopword", "tampilkan semua yang ada", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.Search(tt.query, tt.limit)
+			var ids []string
+			for _, c := range got {
+				ids = append(ids, c.ID)
+				if c.SparseScore <= 0 || c.SparseScore > 1 {
+					t.Errorf("skor %s = %f, harus di rentang (0, 1]", c.ID, c.SparseScore)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+// Skor ternormalisasi tidak boleh bergantung pada ukuran korpus: dokumen yang sama untuk query
+// yang sama harus tetap di atas ambang walau korpus membesar dengan dokumen yang tidak relevan.
+func TestBM25SearchScoreScaleIndependentOfCorpusSize(t *testing.T) {
+	base := []string{"saldo tabungan nasabah", "kredit macet"}
+	large := append([]string{}, base...)
+	for i := 0; i < 200; i++ {
+		large = append(large, "laporan transaksi harian teller")
+	}
+
+	small := newBM25Index(base).Search("saldo tabungan", 1)
+	big := newBM25Index(large).Search("saldo tabungan", 1)
+	if len(small) != 1 || len(big) != 1 {
+		t.Fatalf("hasil kosong: small=%v big=%v", small, big)
+	}
+	for _, c := range []ragCandidate{small[0], big[0]} {
+		if c.SparseScore < 0.25 || c.SparseScore > 1 {
+			t.Errorf("skor %f di luar rentang yang diharapkan", c.SparseScore)
+		}
+	}
+}
+
+func TestFuseRRF(t *testing.T) {
+	cand := func(content string) ragCandidate { return ragCandidate{ID: content, Content: content} }
+
+	tests := []struct {
+		name         string
+		dense        []ragCandidate
+		sparse       []ragCandidate
+		denseWeight  float64
+		sparseWeight float64
+		limit        int
+		want         []string
+	}{
+		{
+			name:        "muncul di kedua daftar naik ke atas",
+			dense:       []ragCandidate{cand("a"), cand("b"), cand("c")},
+			sparse:      []ragCandidate{cand("c"), cand("d")},
+			denseWeight: 1, sparseWeight: 1,
+			want: []string{"c", "a", "b", "d"},
+		},
+		{
+			name:        "bobot sparse lebih besar",
+			dense:       []ragCandidate{cand("a")},
+			sparse:      []ragCandidate{cand("b")},
+			denseWeight: 1, sparseWeight: 2,
+			want: []string{"b", "a"},
+		},
+		{
+			name:        "seri mempertahankan urutan dense",
+			dense:       []ragCandidate{cand("a")},
+			sparse:      []ragCandidate{cand("b")},
+			denseWeight: 1, sparseWeight: 1,
+			want: []string{"a", "b"},
+		},
+		{
+			name:        "limit",
+			dense:       []ragCandidate{cand("a"), cand("b"), cand("c")},
+			sparse:      []ragCandidate{cand("c")},
+			denseWeight: 1, sparseWeight: 1,
+			limit: 2,
+			want:  []string{"c", "a"},
+		},
+		{
+			name:        "hanya sparse",
+			sparse:      []ragCandidate{cand("x"), cand("y")},
+			denseWeight: 1, sparseWeight: 1,
+			want: []string{"x", "y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fuseRRF(tt.dense, tt.sparse, tt.denseWeight, tt.sparseWeight, 60, tt.limit)
+			var contents []string
+			for _, c := range got {
+				contents = append(contents, c.Content)
+			}
+			if !reflect.DeepEqual(contents, tt.want) {
+				t.Errorf("fuseRRF = %v, want %v", contents, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseRRFKeepsBothScores(t *testing.T) {
+	dense := []ragCandidate{{ID: "1", Content: "a", DenseScore: 0.8}}
+	sparse := []ragCandidate{{ID: "1", Content: "a", SparseScore: 0.6}}
+	got := fuseRRF(dense, sparse, 1, 1, 60, 0)
+	if len(got) != 1 {
+		t.Fatalf("len = %d, want 1", len(got))
+	}
+	c := got[0]
+	if c.DenseScore != 0.8 || c.SparseScore != 0.6 {
+		t.Errorf("skor dense/sparse hilang: %+v", c)
+	}
+	if want := 2.0 / 61; c.FusedScore != want {
+		t.Errorf("FusedScore = %f, want %f", c.FusedScore, want)
+	}
+}
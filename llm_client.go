@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// llmRequest adalah satu panggilan chat ke LLM (Ollama lokal lalu Groq sebagai fallback).
+type llmRequest struct {
+	Messages    []GroqMessage
+	Temperature float32
+	// JSONMode meminta output berupa satu objek JSON (response_format di Groq, format di Ollama).
+	JSONMode bool
+	// Provider memaksa "ollama" atau "groq"; kosong = Ollama dulu lalu Groq.
+	Provider string
+}
+
+type llmUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type llmCallResult struct {
+	Content  string
+	Provider string
+	Model    string
+	Usage    llmUsage
+}
+
+type groqResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// flattenMessages menggabungkan percakapan menjadi satu prompt untuk /api/generate Ollama.
+func flattenMessages(messages []GroqMessage) string {
+	if len(messages) == 1 {
+		return messages[0].Content
+	}
+	var b strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&b, "### %s\n%s\n\n", strings.ToUpper(m.Role), m.Content)
+	}
+	return b.String()
+}
+
+// callLLM mencoba Ollama lokal (jika dikonfigurasi) lalu beralih ke Groq.
+func callLLM(ctx context.Context, req llmRequest) (llmCallResult, error) {
+	switch req.Provider {
+	case "":
+	case "ollama":
+		if AppConfig.OllamaURL == "" {
+			return llmCallResult{}, errors.New("OLLAMA_URL tidak dikonfigurasi")
+		}
+		return callOllama(ctx, req)
+	case "groq":
+		if AppConfig.GroqAPIKey == "" {
+			return llmCallResult{}, errors.New("GROQ_API_KEY tidak dikonfigurasi")
+		}
+		return callGroq(ctx, req)
+	default:
+		return llmCallResult{}, fmt.Errorf("provider LLM tidak dikenal: %s", req.Provider)
+	}
+
+	if AppConfig.OllamaURL != "" {
+		result, err := callOllama(ctx, req)
+		if err == nil {
+			log.Println("✅ Sukses mendapatkan respon dari Ollama.")
+			return result, nil
+		}
+		log.Printf("⚠️ %v. Akan beralih ke Groq.", err)
+	}
+
+	if AppConfig.GroqAPIKey == "" {
+		return llmCallResult{}, errors.New("Ollama gagal dan GROQ_API_KEY tidak dikonfigurasi")
+	}
+	log.Println("Menggunakan Layanan Groq AI...")
+	return callGroq(ctx, req)
+}
+
+func callOllama(ctx context.Context, req llmRequest) (llmCallResult, error) {
+	log.Printf("🔄 Mencoba Ollama LLM lokal: %s (model=%s)", AppConfig.OllamaURL, AppConfig.OllamaModel)
+
+	ollamaReq := map[string]any{
+		"model":   AppConfig.OllamaModel,
+		"prompt":  flattenMessages(req.Messages),
+		"stream":  false,
+		"options": map[string]any{"temperature": req.Temperature},
+	}
+	if req.JSONMode {
+		ollamaReq["format"] = "json"
+	}
+
+	// Completion yang gagal di tengah jalan tidak diulang (hanya 429/503) agar tidak generate dua kali
+	resp, respBodyBytes, err := httpClient(depOllama).DoJSON(ctx, http.MethodPost, strings.TrimRight(AppConfig.OllamaURL, "/")+"/api/generate", ollamaReq, nil, withIdempotent(false))
+	if err != nil {
+		return llmCallResult{}, fmt.Errorf("gagal koneksi ke Ollama: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return llmCallResult{}, fmt.Errorf("Ollama error status %d: %s", resp.StatusCode, string(respBodyBytes))
+	}
+
+	var ollamaResp map[string]any
+	if err := json.Unmarshal(respBodyBytes, &ollamaResp); err != nil {
+		return llmCallResult{}, fmt.Errorf("respon Ollama tidak valid: %w", err)
+	}
+
+	result := llmCallResult{Provider: "ollama", Model: AppConfig.OllamaModel}
+	if r, ok := ollamaResp["response"].(string); ok {
+		result.Content = r
+	} else if t, ok := ollamaResp["text"].(string); ok {
+		result.Content = t
+	} else if gens, ok := ollamaResp["generations"].([]any); ok && len(gens) > 0 {
+		if first, ok := gens[0].(map[string]any); ok {
+			if c, ok := first["content"].(string); ok {
+				result.Content = c
+			}
+		}
+	}
+	if result.Content == "" {
+		return llmCallResult{}, errors.New("respon Ollama kosong/format salah")
+	}
+
+	if n, ok := ollamaResp["prompt_eval_count"].(float64); ok {
+		result.Usage.PromptTokens = int(n)
+	}
+	if n, ok := ollamaResp["eval_count"].(float64); ok {
+		result.Usage.CompletionTokens = int(n)
+	}
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return result, nil
+}
+
+func callGroq(ctx context.Context, req llmRequest) (llmCallResult, error) {
+	groqReqBody := GroqRequest{
+		Model:       AppConfig.GroqModel,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+	}
+	if req.JSONMode {
+		groqReqBody.ResponseFormat = &groqResponseFormat{Type: "json_object"}
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+AppConfig.GroqAPIKey)
+	// Tidak diulang setelah error jaringan/5xx: completion yang sudah diproses tetap dihitung kuota token
+	resp, respBodyBytes, err := httpClient(depGroq).DoJSON(ctx, http.MethodPost, AppConfig.GroqAPIURL, groqReqBody, header, withIdempotent(false))
+	if err != nil {
+		return llmCallResult{}, fmt.Errorf("gagal memanggil Groq: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return llmCallResult{}, fmt.Errorf("Groq merespon dengan error (status %d): %s", resp.StatusCode, string(respBodyBytes))
+	}
+
+	var groqResp GroqResponse
+	if err := json.Unmarshal(respBodyBytes, &groqResp); err != nil {
+		return llmCallResult{}, err
+	}
+	if len(groqResp.Choices) == 0 {
+		return llmCallResult{}, errors.New("AI Groq tidak memberikan balasan")
+	}
+
+	return llmCallResult{
+		Content:  groqResp.Choices[0].Message.Content,
+		Provider: "groq",
+		Model:    AppConfig.GroqModel,
+		Usage:    groqResp.Usage,
+	}, nil
+}
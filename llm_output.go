@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LLMSQLOutput adalah kontrak JSON yang wajib dikembalikan LLM untuk setiap pertanyaan.
+type LLMSQLOutput struct {
+	Reasoning     string                `json:"reasoning"`
+	SQL           string                `json:"sql"`
+	TablesUsed    []string              `json:"tables_used"`
+	Confidence    *float64              `json:"confidence"`
+	Clarification *ClarificationRequest `json:"clarification"`
+}
+
+// ClarificationRequest adalah hasil LLM ketika pertanyaan tidak bisa dijawab tanpa info tambahan
+// (periode tidak disebut, jenis rekening tidak jelas, nama nasabah cocok dengan banyak orang, dst).
+type ClarificationRequest struct {
+	Reason      string   `json:"reason"`
+	Questions   []string `json:"questions"`
+	Rephrasings []string `json:"rephrasings"`
+}
+
+// normalizeClarification membuang entri kosong; klarifikasi tanpa pertanyaan maupun
+// rephrasing dianggap tidak ada.
+func normalizeClarification(c *ClarificationRequest) *ClarificationRequest {
+	if c == nil {
+		return nil
+	}
+	clean := func(items []string) []string {
+		var out []string
+		for _, it := range items {
+			if it = strings.TrimSpace(it); it != "" {
+				out = append(out, it)
+			}
+		}
+		return out
+	}
+	c.Reason = strings.TrimSpace(c.Reason)
+	c.Questions = clean(c.Questions)
+	c.Rephrasings = clean(c.Rephrasings)
+	if len(c.Questions) == 0 && len(c.Rephrasings) == 0 {
+		return nil
+	}
+	return c
+}
+
+// llmOutputInstructions menjelaskan skema JSON yang harus diikuti LLM.
+func llmOutputInstructions() string {
+	return `
+== FORMAT JAWABAN (WAJIB JSON) ==
+Balas HANYA dengan satu objek JSON valid (tanpa markdown, tanpa teks lain) dengan skema:
+{
+  "reasoning": "analisis singkat: intent user, ID referensi yang dipakai, tabel yang di-JOIN dan kondisi WHERE",
+  "sql": "satu query SELECT PostgreSQL lengkap, atau string kosong jika meminta klarifikasi",
+  "tables_used": ["nama_tabel", "..."],
+  "confidence": 0.0 sampai 1.0 (seberapa yakin query menjawab pertanyaan dengan tepat),
+  "clarification": null
+}
+`
+}
+
+// parseLLMSQLOutput mem-parse dan memvalidasi respons LLM terhadap kontrak LLMSQLOutput.
+// Error yang dikembalikan dikirim balik ke LLM saat repair retry, jadi harus jelas.
+func parseLLMSQLOutput(rawContent string) (LLMSQLOutput, error) {
+	var out LLMSQLOutput
+
+	// Objek JSON pertama dibaca utuh; teks sebelum "{" (mis. pagar ```json) dan setelah objek
+	// selesai (penutup markdown, catatan tambahan) diabaikan.
+	body := strings.TrimSpace(rawContent)
+	start := strings.Index(body, "{")
+	if start == -1 {
+		return out, errors.New("respons bukan objek JSON")
+	}
+	if err := json.NewDecoder(strings.NewReader(body[start:])).Decode(&out); err != nil {
+		return out, fmt.Errorf("JSON tidak valid: %v", err)
+	}
+
+	if out.Confidence == nil {
+		return out, errors.New(`field "confidence" wajib diisi`)
+	}
+	if *out.Confidence < 0 || *out.Confidence > 1 {
+		return out, fmt.Errorf(`field "confidence" harus di antara 0 dan 1, bukan %v`, *out.Confidence)
+	}
+
+	out.Reasoning = strings.TrimSpace(out.Reasoning)
+	out.Clarification = normalizeClarification(out.Clarification)
+	if out.Clarification != nil && !AppConfig.ClarificationEnabled {
+		out.Clarification = nil
+	}
+	if out.Clarification != nil {
+		out.SQL = ""
+		return out, nil
+	}
+
+	if strings.TrimSpace(out.SQL) == "" {
+		return out, errors.New(`field "sql" kosong padahal "clarification" tidak diisi`)
+	}
+	cleaned := sanitizeSQL(out.SQL)
+	if cleaned == "" {
+		return out, errors.New(`field "sql" harus berupa satu query SELECT read-only`)
+	}
+	out.SQL = strings.TrimSpace(cleaned)
+
+	tables := out.TablesUsed[:0]
+	for _, t := range out.TablesUsed {
+		if t = strings.TrimSpace(t); t != "" {
+			tables = append(tables, t)
+		}
+	}
+	out.TablesUsed = tables
+	return out, nil
+}
+
+// generateLLMSQLOutput memanggil LLM dalam mode JSON dan, jika output tidak sesuai kontrak,
+// mengirim ulang output tersebut beserta pesan error-nya agar diperbaiki (maks LLM_REPAIR_RETRIES kali).
+func generateLLMSQLOutput(ctx context.Context, prompt string, temperature float32, provider string) (LLMSQLOutput, llmCallResult, error) {
+	messages := []GroqMessage{{Role: "user", Content: prompt}}
+	var total llmUsage
+
+	for attempt := 0; ; attempt++ {
+		result, err := callLLM(ctx, llmRequest{Messages: messages, Temperature: temperature, JSONMode: true, Provider: provider})
+		if err != nil {
+			return LLMSQLOutput{}, result, err
+		}
+		total.PromptTokens += result.Usage.PromptTokens
+		total.CompletionTokens += result.Usage.CompletionTokens
+		total.TotalTokens += result.Usage.TotalTokens
+		result.Usage = total
+		log.Printf("🤖 RAW AI Response (%s):\n%s\n", result.Provider, result.Content)
+
+		out, parseErr := parseLLMSQLOutput(result.Content)
+		if parseErr == nil {
+			return out, result, nil
+		}
+		if attempt >= AppConfig.LLMRepairRetries {
+			return LLMSQLOutput{}, result, fmt.Errorf("output AI tidak sesuai kontrak JSON: %w", parseErr)
+		}
+
+		log.Printf("⚠️ Output AI tidak valid (%v). Meminta perbaikan (percobaan %d)...", parseErr, attempt+1)
+		messages = append(messages,
+			GroqMessage{Role: "assistant", Content: result.Content},
+			GroqMessage{Role: "user", Content: fmt.Sprintf(
+				"Output Anda tidak valid: %v. Balas ulang HANYA dengan satu objek JSON sesuai skema FORMAT JAWABAN.", parseErr)},
+		)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeClarification(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *ClarificationRequest
+		want *ClarificationRequest
+	}{
+		{"nil", nil, nil},
+		{"kosong dianggap tidak ada", &ClarificationRequest{Reason: "kurang jelas"}, nil},
+		{"hanya spasi dianggap tidak ada", &ClarificationRequest{Questions: []string{" ", ""}, Rephrasings: []string{"\n"}}, nil},
+		{
+			"entri kosong dibuang dan teks dirapikan",
+			&ClarificationRequest{Reason: "  periode tidak disebut ", Questions: []string{" bulan apa? ", ""}},
+			&ClarificationRequest{Reason: "periode tidak disebut", Questions: []string{"bulan apa?"}},
+		},
+		{
+			"hanya rephrasing tetap valid",
+			&ClarificationRequest{Rephrasings: []string{"saldo tabungan bulan ini"}},
+			&ClarificationRequest{Rephrasings: []string{"saldo tabungan bulan ini"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeClarification(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeClarification = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLLMSQLOutput(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+
+	tests := []struct {
+		name          string
+		clarification bool
+		raw           string
+		wantSQL       string
+		wantTables    []string
+		wantClarify   bool
+		wantErr       bool
+	}{
+		{
+			name:       "JSON polos",
+			raw:        `{"reasoning":" hitung ","sql":"SELECT COUNT(*) FROM nasabah","tables_used":["nasabah"," "],"confidence":0.9,"clarification":null}`,
+			wantSQL:    "SELECT COUNT(*) FROM nasabah",
+			wantTables: []string{"nasabah"},
+		},
+		{
+			name:       "dibungkus pagar markdown",
+			raw:        "```json\n{\"sql\":\"SELECT 1\",\"tables_used\":[],\"confidence\":0.5}\n```",
+			wantSQL:    "SELECT 1",
+			wantTables: []string{},
+		},
+		{
+			name:       "teks dengan kurung kurawal setelah objek",
+			raw:        `{"sql":"SELECT 1","tables_used":[],"confidence":0.5} Catatan: format {tanggal} mengikuti ISO.`,
+			wantSQL:    "SELECT 1",
+			wantTables: []string{},
+		},
+		{
+			name:       "kurung kurawal di dalam string",
+			raw:        `{"reasoning":"pakai {id}","sql":"SELECT '{x}' AS v","tables_used":[],"confidence":0.7}`,
+			wantSQL:    "SELECT '{x}' AS v",
+			wantTables: []string{},
+		},
+		{
+			name:       "komentar SQL dibuang",
+			raw:        `{"sql":"-- jumlah\nSELECT 1","tables_used":[],"confidence":0.5}`,
+			wantSQL:    "SELECT 1",
+			wantTables: []string{},
+		},
+		{
+			name:          "klarifikasi mengosongkan SQL",
+			clarification: true,
+			raw:           `{"sql":"SELECT 1","confidence":0.2,"clarification":{"reason":"periode?","questions":["bulan apa?"]}}`,
+			wantClarify:   true,
+		},
+		{
+			name:       "klarifikasi diabaikan jika dimatikan",
+			raw:        `{"sql":"SELECT 1","tables_used":[],"confidence":0.2,"clarification":{"questions":["bulan apa?"]}}`,
+			wantSQL:    "SELECT 1",
+			wantTables: []string{},
+		},
+		{name: "bukan JSON", raw: "SELECT * FROM nasabah", wantErr: true},
+		{name: "JSON terpotong", raw: `{"sql":"SELECT 1","confidence":0.5`, wantErr: true},
+		{name: "confidence hilang", raw: `{"sql":"SELECT 1"}`, wantErr: true},
+		{name: "confidence di luar rentang", raw: `{"sql":"SELECT 1","confidence":1.5}`, wantErr: true},
+		{name: "SQL kosong tanpa klarifikasi", raw: `{"sql":" ","confidence":0.5}`, wantErr: true},
+		{name: "SQL bukan SELECT", raw: `{"sql":"DELETE FROM nasabah","confidence":0.9}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = &Config{ClarificationEnabled: tt.clarification}
+			got, err := parseLLMSQLOutput(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLLMSQLOutput error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.SQL != tt.wantSQL {
+				t.Errorf("SQL = %q, want %q", got.SQL, tt.wantSQL)
+			}
+			if (got.Clarification != nil) != tt.wantClarify {
+				t.Errorf("Clarification = %+v, want ada=%t", got.Clarification, tt.wantClarify)
+			}
+			if !tt.wantClarify && !reflect.DeepEqual(got.TablesUsed, tt.wantTables) {
+				t.Errorf("TablesUsed = %#v, want %#v", got.TablesUsed, tt.wantTables)
+			}
+		})
+	}
+}
@@ -15,10 +15,10 @@ type QueryResult struct {
 	Rows    [][]interface{} `json:"rows"`
 }
 
-func GetSQL(userPrompt string) (AISqlResponse, error) {
+func GetSQL(ctx context.Context, userPrompt string, opts SQLGenOptions) (AISqlResponse, error) {
 	log.Println("Memanggil AI Service (dengan semantic cache)...")
 
-	aiResp, err := getSQLFromAI_Groq(userPrompt)
+	aiResp, err := getSQLFromAI_Groq(ctx, userPrompt, opts)
 	if err != nil {
 		return AISqlResponse{}, err
 	}
@@ -26,6 +26,15 @@ func GetSQL(userPrompt string) (AISqlResponse, error) {
 		return AISqlResponse{}, errors.New("AI tidak mengembalikan query SQL.")
 	}
 
+	if !aiResp.IsAmbiguous {
+		snap, err := CurrentSchemaSnapshot(ctx)
+		if err != nil {
+			log.Printf("PERINGATAN: Validasi tabel untuk confidence dilewati: %v", err)
+		}
+		aiResp.Confidence = ScoreConfidence(aiResp, snap)
+		log.Printf("📊 Confidence %.3f (source=%s, warnings=%d)", aiResp.Confidence.Score, aiResp.Confidence.Source, len(aiResp.Confidence.Warnings))
+	}
+
 	return aiResp, nil
 }
 
@@ -108,18 +117,24 @@ func BuildDynamicQuery(req QueryRequest) (string, []interface{}, error) {
 	return query.String(), params, nil
 }
 
-func ExecuteDynamicQuery(query string, params []interface{}) (QueryResult, error) {
-	var result QueryResult
+func ExecuteDynamicQuery(ctx context.Context, query string, params []interface{}) (QueryResult, error) {
+	result, _, err := executeReadOnlyQuery(ctx, query, params, 0)
+	return result, err
+}
+
+// executeReadOnlyQuery menjalankan query di transaksi read-only. Jika maxRows > 0, pembacaan
+// berhenti setelah maxRows baris dan truncated bernilai true bila masih ada baris tersisa.
+func executeReadOnlyQuery(ctx context.Context, query string, params []interface{}, maxRows int) (result QueryResult, truncated bool, err error) {
 	cleanQuery := strings.TrimSpace(strings.ToUpper(query))
 
 	if !strings.HasPrefix(cleanQuery, "SELECT") && !strings.HasPrefix(cleanQuery, "WITH") {
-		return result, fmt.Errorf("KEAMANAN: Hanya query SELECT yang diizinkan. Query Anda: %s", query)
+		return result, false, fmt.Errorf("KEAMANAN: Hanya query SELECT yang diizinkan. Query Anda: %s", query)
 	}
 
 	forbidden := []string{"DROP ", "DELETE ", "UPDATE ", "INSERT ", "TRUNCATE ", "ALTER ", "GRANT ", "REVOKE "}
 	for _, word := range forbidden {
 		if strings.Contains(cleanQuery, word) {
-			return result, fmt.Errorf("KEAMANAN: Ditemukan kata kunci terlarang '%s'", word)
+			return result, false, fmt.Errorf("KEAMANAN: Ditemukan kata kunci terlarang '%s'", word)
 		}
 	}
 
@@ -132,7 +147,7 @@ func ExecuteDynamicQuery(query string, params []interface{}) (QueryResult, error
 		timeout = AppConfig.QueryTimeout
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	txOptions := &sql.TxOptions{
@@ -142,24 +157,28 @@ func ExecuteDynamicQuery(query string, params []interface{}) (QueryResult, error
 
 	tx, err := DbInstance.BeginTx(ctx, txOptions)
 	if err != nil {
-		return result, fmt.Errorf("gagal memulai transaksi read-only: %w", err)
+		return result, false, fmt.Errorf("gagal memulai transaksi read-only: %w", err)
 	}
 	defer tx.Rollback()
 	rows, err := tx.QueryContext(ctx, query, params...)
 	if err != nil {
 		log.Printf("Error eksekusi query: %v. Query: %s", err, query)
-		return result, fmt.Errorf("gagal mengeksekusi query (mungkin query tidak valid atau melanggar aturan read-only)")
+		return result, false, fmt.Errorf("gagal mengeksekusi query (mungkin query tidak valid atau melanggar aturan read-only)")
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return result, err
+		return result, false, err
 	}
 	result.Columns = columns
 	result.Rows = make([][]interface{}, 0)
 
 	for rows.Next() {
+		if maxRows > 0 && len(result.Rows) >= maxRows {
+			truncated = true
+			break
+		}
 		rowValues := make([]interface{}, len(columns))
 
 		rowScanners := make([]interface{}, len(columns))
@@ -168,11 +187,11 @@ func ExecuteDynamicQuery(query string, params []interface{}) (QueryResult, error
 		}
 
 		if err := rows.Scan(rowScanners...); err != nil {
-			return result, err
+			return result, false, err
 		}
 
 		result.Rows = append(result.Rows, rowValues)
 	}
 
-	return result, nil
+	return result, truncated, rows.Err()
 }
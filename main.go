@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	os.Exit(runCLI(os.Args[1:]))
+}
+
+// bootstrapOptions mengatur langkah opsional saat bootstrap.
+type bootstrapOptions struct {
+	// autoMigrate menjalankan migrasi tabel metadata jika MIGRATE_ON_START aktif
+	autoMigrate bool
+}
+
+var defaultBootstrapOptions = bootstrapOptions{autoMigrate: true}
+
+// bootstrap adalah satu-satunya jalur inisialisasi (config, DB, layanan vektor) yang dipakai
+// oleh server maupun semua subcommand CLI.
+func bootstrap(opts bootstrapOptions) error {
 	// Load .env file
 	err := godotenv.Load()
 	if err != nil {
@@ -18,21 +34,58 @@ func main() {
 	}
 
 	// Load configuration from environment variables
-	_, err = LoadConfig()
-	if err != nil {
-		log.Fatalf("Fatal Error: Gagal memuat konfigurasi: %v", err)
+	if _, err := LoadConfig(); err != nil {
+		return fmt.Errorf("gagal memuat konfigurasi: %w", err)
 	}
 	log.Println("✅ Konfigurasi berhasil dimuat dari environment variables")
 
 	// Connect to database
-	err = ConnectDB()
-	if err != nil {
-		log.Fatalf("Fatal Error: Gagal koneksi ke database. %v", err)
+	if err := ConnectDB(); err != nil {
+		return fmt.Errorf("gagal koneksi ke database: %w", err)
 	}
 
-	// Initialize vector service (Qdrant + Google AI)
+	// Create/evolve the service's own tables in the metadata schema
+	if opts.autoMigrate && AppConfig.MigrateOnStart {
+		if _, err := MigrateUp(context.Background()); err != nil {
+			return fmt.Errorf("gagal menjalankan migrasi: %w", err)
+		}
+	}
+
+	// Initialize vector service (Qdrant + Google AI); Qdrant yang belum tersedia hanya membuat
+	// layanan berjalan dalam mode degraded
 	if err := InitVectorService(); err != nil {
-		log.Fatalf("Fatal Error: Gagal koneksi ke Qdrant (Database Vektor): %v", err)
+		return fmt.Errorf("gagal inisialisasi layanan vektor: %w", err)
+	}
+	return nil
+}
+
+func runServe() error {
+	if err := bootstrap(defaultBootstrapOptions); err != nil {
+		return err
+	}
+
+	// Seed built-in & file-based SQL examples into rag_sql_examples
+	if AppConfig.SeedExamplesOnStart {
+		if _, err := SeedSqlExamples(context.Background(), AppConfig.SeedExamplesDir); err != nil {
+			log.Printf("PERINGATAN: Gagal seed rag_sql_examples: %v", err)
+		}
+	}
+
+	// Load prompt templates (built-in + PROMPT_TEMPLATES_DIR) and validate PROMPT_ROLLOUT
+	if _, err := ReloadPromptTemplates(); err != nil {
+		return fmt.Errorf("gagal memuat template prompt: %w", err)
+	}
+
+	// Load schema snapshot and keep it fresh (interval + LISTEN/NOTIFY)
+	StartSchemaSnapshotRefresher(context.Background())
+
+	// Reconnect Qdrant di background selama mode degraded; indeks BM25 lokal langsung dibangun
+	// karena menjadi satu-satunya sumber contoh SQL sampai Qdrant kembali
+	StartVectorReconnector(context.Background())
+	if VectorServiceDegraded() {
+		if err := RefreshSparseIndex(); err != nil {
+			log.Printf("PERINGATAN: Gagal membangun indeks BM25 untuk mode degraded: %v", err)
+		}
 	}
 
 	// Register HTTP routes
@@ -42,5 +95,5 @@ func main() {
 	// Start server
 	addr := fmt.Sprintf("%s:%s", AppConfig.ServerHost, AppConfig.ServerPort)
 	log.Printf("Server web berjalan di http://%s\n", addr)
-	log.Fatal(http.ListenAndServe(":"+AppConfig.ServerPort, nil))
+	return http.ListenAndServe(":"+AppConfig.ServerPort, nil)
 }
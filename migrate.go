@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Kunci advisory lock agar dua instance tidak menjalankan migrasi bersamaan.
+const migrationLockID = 7_310_035
+
+var (
+	migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	plainIdentRe    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+type migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// metaSchema adalah skema tempat tabel milik service (bukan data perbankan) disimpan.
+func metaSchema() string {
+	if AppConfig != nil && AppConfig.MetaSchema != "" {
+		return AppConfig.MetaSchema
+	}
+	return "ai_meta"
+}
+
+// metaTable mengembalikan nama tabel metadata yang sudah dikualifikasi skema.
+func metaTable(name string) string {
+	return quoteIdent(metaSchema()) + "." + name
+}
+
+func loadMigrations() ([]migration, error) {
+	return loadMigrationsFrom(migrationFiles)
+}
+
+// loadMigrationsFrom memasangkan file NNNN_nama.up.sql/.down.sql dari direktori migrations
+// di fsys dan mengurutkannya berdasarkan versi.
+func loadMigrationsFrom(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("nama file migrasi tidak valid: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrasi %04d_%s tidak punya file .up.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(a, b int) bool {
+		return migrations[a].Version < migrations[b].Version
+	})
+	return migrations, nil
+}
+
+// renderMigration mengisi placeholder {{.MetaSchema}} dan {{.DataSchema}} pada file migrasi.
+func renderMigration(content string) (string, error) {
+	dataSchema, err := getSchemaFromConnStr()
+	if err != nil {
+		return "", err
+	}
+	vars := map[string]string{"MetaSchema": metaSchema(), "DataSchema": dataSchema}
+	for key, v := range vars {
+		if !plainIdentRe.MatchString(v) {
+			return "", fmt.Errorf("nama skema %s '%s' tidak valid untuk migrasi", key, v)
+		}
+	}
+
+	tmpl, err := template.New("migration").Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// withMigrationLock menyiapkan tabel schema_migrations lalu menjalankan fn di bawah advisory lock.
+func withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	if DbInstance == nil {
+		return fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	conn, err := DbInstance.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("gagal mengambil lock migrasi: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(`
+	CREATE SCHEMA IF NOT EXISTS %s;
+	CREATE TABLE IF NOT EXISTS %s (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	`, quoteIdent(metaSchema()), metaTable("schema_migrations"))); err != nil {
+		return fmt.Errorf("gagal menyiapkan tabel schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func loadAppliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(
+		"SELECT version, checksum, applied_at FROM %s", metaTable("schema_migrations")))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// MigrateUp menjalankan semua migrasi yang belum diterapkan. Migrasi yang sudah diterapkan
+// tetapi isinya berubah (checksum berbeda) dianggap error.
+func MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	appliedCount := 0
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadAppliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if a, ok := applied[m.Version]; ok {
+				if a.Checksum != m.Checksum {
+					return fmt.Errorf("checksum migrasi %04d_%s berubah sejak diterapkan", m.Version, m.Name)
+				}
+				continue
+			}
+
+			script, err := renderMigration(m.Up)
+			if err != nil {
+				return err
+			}
+			if err := runMigrationTx(ctx, conn, script, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, fmt.Sprintf(
+					"INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)", metaTable("schema_migrations")),
+					m.Version, m.Name, m.Checksum)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migrasi %04d_%s gagal: %w", m.Version, m.Name, err)
+			}
+			log.Printf("✅ Migrasi %04d_%s diterapkan.", m.Version, m.Name)
+			appliedCount++
+		}
+		return nil
+	})
+	return appliedCount, err
+}
+
+// MigrateDown membatalkan sejumlah steps migrasi terakhir.
+func MigrateDown(ctx context.Context, steps int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	rolledBack := 0
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadAppliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migrasi %04d_%s tidak punya file .down.sql", m.Version, m.Name)
+			}
+
+			script, err := renderMigration(m.Down)
+			if err != nil {
+				return err
+			}
+			if err := runMigrationTx(ctx, conn, script, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, fmt.Sprintf(
+					"DELETE FROM %s WHERE version = $1", metaTable("schema_migrations")), m.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("rollback migrasi %04d_%s gagal: %w", m.Version, m.Name, err)
+			}
+			log.Printf("✅ Migrasi %04d_%s dibatalkan.", m.Version, m.Name)
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// MigrationStatuses mengembalikan daftar migrasi beserta status penerapannya.
+func MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadAppliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			s := MigrationStatus{Version: m.Version, Name: m.Name}
+			if a, ok := applied[m.Version]; ok {
+				s.Applied = true
+				appliedAt := a.AppliedAt
+				s.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func runMigrationTx(ctx context.Context, conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrationsFrom(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+
+	tests := []struct {
+		name         string
+		files        fstest.MapFS
+		wantVersions []int
+		wantDown     []bool
+		wantErr      string
+	}{
+		{
+			name: "up dan down dipasangkan, diurutkan per versi",
+			files: fstest.MapFS{
+				"migrations/0010_kamus.up.sql":    file("CREATE TABLE kamus();"),
+				"migrations/0002_contoh.up.sql":   file("CREATE TABLE contoh();"),
+				"migrations/0002_contoh.down.sql": file("DROP TABLE contoh;"),
+				"migrations/0001_awal.down.sql":   file("DROP SCHEMA meta;"),
+				"migrations/0001_awal.up.sql":     file("CREATE SCHEMA meta;"),
+				"migrations/0003_indeks.up.sql":   file("CREATE INDEX i ON contoh(x);"),
+				"migrations/0003_indeks.down.sql": file("DROP INDEX i;"),
+			},
+			wantVersions: []int{1, 2, 3, 10},
+			wantDown:     []bool{true, true, true, false},
+		},
+		{
+			name: "down tanpa up ditolak",
+			files: fstest.MapFS{
+				"migrations/0001_awal.up.sql":    file("SELECT 1;"),
+				"migrations/0002_yatim.down.sql": file("SELECT 1;"),
+			},
+			wantErr: "0002_yatim tidak punya file .up.sql",
+		},
+		{
+			name: "nama file tidak valid",
+			files: fstest.MapFS{
+				"migrations/tambah_kolom.sql": file("SELECT 1;"),
+			},
+			wantErr: "nama file migrasi tidak valid",
+		},
+		{
+			name:    "direktori migrations tidak ada",
+			files:   fstest.MapFS{},
+			wantErr: "migrations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadMigrationsFrom(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want mengandung %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if len(got) != len(tt.wantVersions) {
+				t.Fatalf("jumlah migrasi = %d, want %d", len(got), len(tt.wantVersions))
+			}
+			for i, m := range got {
+				if m.Version != tt.wantVersions[i] {
+					t.Errorf("migrasi[%d].Version = %d, want %d", i, m.Version, tt.wantVersions[i])
+				}
+				if (m.Down != "") != tt.wantDown[i] {
+					t.Errorf("migrasi %04d_%s: ada down = %v, want %v", m.Version, m.Name, m.Down != "", tt.wantDown[i])
+				}
+				if m.Up == "" || m.Checksum == "" {
+					t.Errorf("migrasi %04d_%s tanpa isi up/checksum", m.Version, m.Name)
+				}
+			}
+		})
+	}
+}
+
+// Checksum hanya bergantung pada isi file up, sehingga mengubah file down tidak dianggap
+// mengubah migrasi yang sudah diterapkan.
+func TestLoadMigrationsChecksumFromUpOnly(t *testing.T) {
+	load := func(down string) string {
+		t.Helper()
+		ms, err := loadMigrationsFrom(fstest.MapFS{
+			"migrations/0001_awal.up.sql":   {Data: []byte("CREATE SCHEMA meta;")},
+			"migrations/0001_awal.down.sql": {Data: []byte(down)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ms[0].Checksum
+	}
+	if load("DROP SCHEMA meta;") != load("DROP SCHEMA meta CASCADE;") {
+		t.Error("checksum berubah karena file down")
+	}
+}
+
+func TestEmbeddedMigrationsRender(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{MetaSchema: "ai_meta"}
+	t.Setenv("DB_CONN_STRING", "postgres://u:p@localhost/bank?search_path=core_bank")
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("versi migrasi tidak berurutan: posisi %d berisi %04d_%s", i, m.Version, m.Name)
+		}
+		if m.Down == "" {
+			t.Errorf("migrasi %04d_%s tidak punya file .down.sql", m.Version, m.Name)
+		}
+		for kind, content := range map[string]string{"up": m.Up, "down": m.Down} {
+			out, err := renderMigration(content)
+			if err != nil {
+				t.Errorf("render %04d_%s.%s: %v", m.Version, m.Name, kind, err)
+				continue
+			}
+			if strings.Contains(out, "{{") {
+				t.Errorf("render %04d_%s.%s masih berisi placeholder", m.Version, m.Name, kind)
+			}
+		}
+	}
+}
+
+func TestRenderMigrationRejectsInvalidSchema(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	t.Setenv("DB_CONN_STRING", "postgres://u:p@localhost/bank?search_path=core_bank")
+
+	AppConfig = &Config{MetaSchema: "ai_meta; DROP SCHEMA core_bank"}
+	if _, err := renderMigration("CREATE SCHEMA {{.MetaSchema}};"); err == nil {
+		t.Error("nama skema metadata tidak valid harus ditolak")
+	}
+
+	AppConfig = &Config{MetaSchema: "ai_meta"}
+	got, err := renderMigration("ALTER TABLE {{.DataSchema}}.x SET SCHEMA {{.MetaSchema}};")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ALTER TABLE core_bank.x SET SCHEMA ai_meta;"; got != want {
+		t.Errorf("renderMigration = %q, want %q", got, want)
+	}
+}
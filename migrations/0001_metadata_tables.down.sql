@@ -0,0 +1,23 @@
+-- Tabel yang dipindahkan dari skema data dikembalikan ke skema asal (tanpa kolom yang ditambahkan
+-- migrasi up); hanya tabel yang dibuat oleh migrasi ini yang dihapus.
+DO $$
+DECLARE
+    t     TEXT;
+    c     TEXT;
+    moved {{.MetaSchema}}.moved_legacy_tables%ROWTYPE;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['ai_reference_tables', 'absurd_keywords', 'ai_dictionary', 'rag_sql_examples'] LOOP
+        SELECT * INTO moved FROM {{.MetaSchema}}.moved_legacy_tables WHERE table_name = t;
+        IF FOUND THEN
+            FOREACH c IN ARRAY moved.added_columns LOOP
+                EXECUTE format('ALTER TABLE {{.MetaSchema}}.%I DROP COLUMN IF EXISTS %I', t, c);
+            END LOOP;
+            EXECUTE format('ALTER TABLE {{.MetaSchema}}.%I SET SCHEMA %I', t, moved.original_schema);
+        ELSE
+            EXECUTE format('DROP TABLE IF EXISTS {{.MetaSchema}}.%I', t);
+        END IF;
+    END LOOP;
+END;
+$$;
+
+DROP TABLE IF EXISTS {{.MetaSchema}}.moved_legacy_tables;
@@ -0,0 +1,75 @@
+-- Tabel milik service (contoh SQL RAG, kamus bisnis, kata kunci absurd, tabel referensi)
+-- disimpan di skema metadata, terpisah dari skema data perbankan.
+CREATE SCHEMA IF NOT EXISTS {{.MetaSchema}};
+
+-- Tabel yang dipindahkan dari skema data beserta kolom yang ditambahkan migrasi ini, agar
+-- migrasi down mengembalikannya ke skema asal alih-alih menghapus data yang sudah ada.
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.moved_legacy_tables (
+    table_name      TEXT PRIMARY KEY,
+    original_schema TEXT NOT NULL,
+    added_columns   TEXT[] NOT NULL DEFAULT '{}',
+    moved_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Instalasi lama membuat tabel-tabel ini di skema data; pindahkan jika belum ada di skema metadata.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['rag_sql_examples', 'ai_dictionary', 'absurd_keywords', 'ai_reference_tables'] LOOP
+        IF to_regclass(format('%I.%I', '{{.DataSchema}}', t)) IS NOT NULL
+           AND to_regclass(format('%I.%I', '{{.MetaSchema}}', t)) IS NULL THEN
+            EXECUTE format('ALTER TABLE %I.%I SET SCHEMA %I', '{{.DataSchema}}', t, '{{.MetaSchema}}');
+            INSERT INTO {{.MetaSchema}}.moved_legacy_tables (table_name, original_schema)
+            VALUES (t, '{{.DataSchema}}');
+        END IF;
+    END LOOP;
+END;
+$$;
+
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.rag_sql_examples (
+    id             BIGSERIAL PRIMARY KEY,
+    prompt_example TEXT NOT NULL,
+    sql_example    TEXT NOT NULL
+);
+
+-- Catat kolom yang benar-benar baru pada tabel lama, supaya down tidak menghapus kolom asli.
+DO $$
+DECLARE
+    c TEXT;
+BEGIN
+    FOREACH c IN ARRAY ARRAY['source', 'created_at'] LOOP
+        IF NOT EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = lower('{{.MetaSchema}}') AND table_name = 'rag_sql_examples' AND column_name = c
+        ) THEN
+            UPDATE {{.MetaSchema}}.moved_legacy_tables
+            SET added_columns = array_append(added_columns, c)
+            WHERE table_name = 'rag_sql_examples';
+        END IF;
+    END LOOP;
+END;
+$$;
+ALTER TABLE {{.MetaSchema}}.rag_sql_examples ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'manual';
+ALTER TABLE {{.MetaSchema}}.rag_sql_examples ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.ai_dictionary (
+    id              BIGSERIAL PRIMARY KEY,
+    istilah         TEXT NOT NULL,
+    definisi_bisnis TEXT NOT NULL,
+    logika_sql      TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.absurd_keywords (
+    id        BIGSERIAL PRIMARY KEY,
+    keyword   TEXT NOT NULL,
+    category  TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true
+);
+
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.ai_reference_tables (
+    table_name   TEXT PRIMARY KEY,
+    id_column    TEXT,
+    label_column TEXT,
+    is_active    BOOLEAN NOT NULL DEFAULT true
+);
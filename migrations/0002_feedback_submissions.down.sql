@@ -0,0 +1 @@
+DROP TABLE IF EXISTS {{.MetaSchema}}.feedback_submissions;
@@ -0,0 +1,19 @@
+-- Koreksi SQL dari user ditampung dulu sebagai submission dan baru masuk ke
+-- rag_sql_examples setelah disetujui admin.
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.feedback_submissions (
+    id               BIGSERIAL PRIMARY KEY,
+    prompt_asli      TEXT NOT NULL,
+    generated_sql    TEXT,
+    sql_koreksi      TEXT NOT NULL,
+    submitted_by     TEXT,
+    status           TEXT NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'approved', 'rejected')),
+    test_row_count   INTEGER,
+    reviewed_by      TEXT,
+    review_note      TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS feedback_submissions_status_idx
+    ON {{.MetaSchema}}.feedback_submissions (status, created_at);
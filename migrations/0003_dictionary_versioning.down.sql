@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS {{.MetaSchema}}.ai_dictionary_history;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary DROP COLUMN IF EXISTS is_active;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary DROP COLUMN IF EXISTS version;
+-- id hanya dihapus jika ditambahkan migrasi up pada tabel lama (tabel baru dari 0001 sudah punya id).
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM {{.MetaSchema}}.moved_legacy_tables
+        WHERE table_name = 'ai_dictionary' AND 'id' = ANY(added_columns)
+    ) THEN
+        ALTER TABLE {{.MetaSchema}}.ai_dictionary DROP COLUMN IF EXISTS id;
+        UPDATE {{.MetaSchema}}.moved_legacy_tables
+        SET added_columns = array_remove(added_columns, 'id')
+        WHERE table_name = 'ai_dictionary';
+    END IF;
+END;
+$$;
@@ -0,0 +1,40 @@
+-- Kamus istilah bisnis dikelola lewat API: setiap perubahan menaikkan version dan
+-- dicatat di ai_dictionary_history. Hapus bersifat soft delete (is_active = false).
+-- Tabel lama yang dipindahkan 0001 bisa belum punya id; catat agar down mengembalikannya seperti semula.
+UPDATE {{.MetaSchema}}.moved_legacy_tables
+SET added_columns = array_append(added_columns, 'id')
+WHERE table_name = 'ai_dictionary'
+  AND NOT ('id' = ANY(added_columns))
+  AND NOT EXISTS (
+      SELECT 1 FROM information_schema.columns
+      WHERE table_schema = lower('{{.MetaSchema}}') AND table_name = 'ai_dictionary' AND column_name = 'id'
+  );
+ALTER TABLE {{.MetaSchema}}.ai_dictionary ADD COLUMN IF NOT EXISTS id BIGSERIAL;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE {{.MetaSchema}}.ai_dictionary ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE {{.MetaSchema}}.ai_dictionary ADD COLUMN IF NOT EXISTS updated_by TEXT;
+
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.ai_dictionary_history (
+    id              BIGSERIAL PRIMARY KEY,
+    dictionary_id   BIGINT NOT NULL,
+    version         INTEGER NOT NULL,
+    action          TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    istilah         TEXT NOT NULL,
+    definisi_bisnis TEXT NOT NULL,
+    logika_sql      TEXT NOT NULL,
+    changed_by      TEXT,
+    changed_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS ai_dictionary_history_item_idx
+    ON {{.MetaSchema}}.ai_dictionary_history (dictionary_id, version);
+
+-- Baris yang sudah ada dicatat sebagai versi awal
+INSERT INTO {{.MetaSchema}}.ai_dictionary_history
+    (dictionary_id, version, action, istilah, definisi_bisnis, logika_sql)
+SELECT d.id, d.version, 'create', d.istilah, d.definisi_bisnis, d.logika_sql
+FROM {{.MetaSchema}}.ai_dictionary d
+WHERE NOT EXISTS (
+    SELECT 1 FROM {{.MetaSchema}}.ai_dictionary_history h WHERE h.dictionary_id = d.id
+);
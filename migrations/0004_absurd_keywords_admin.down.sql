@@ -0,0 +1,15 @@
+ALTER TABLE {{.MetaSchema}}.absurd_keywords DROP COLUMN IF EXISTS updated_at;
+-- id hanya dihapus jika ditambahkan migrasi up pada tabel lama (tabel baru dari 0001 sudah punya id).
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM {{.MetaSchema}}.moved_legacy_tables
+        WHERE table_name = 'absurd_keywords' AND 'id' = ANY(added_columns)
+    ) THEN
+        ALTER TABLE {{.MetaSchema}}.absurd_keywords DROP COLUMN IF EXISTS id;
+        UPDATE {{.MetaSchema}}.moved_legacy_tables
+        SET added_columns = array_remove(added_columns, 'id')
+        WHERE table_name = 'absurd_keywords';
+    END IF;
+END;
+$$;
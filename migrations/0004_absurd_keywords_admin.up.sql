@@ -0,0 +1,12 @@
+-- absurd_keywords dikelola lewat API dan dimuat ke matcher in-memory.
+-- Tabel lama yang dipindahkan 0001 bisa belum punya id; catat agar down mengembalikannya seperti semula.
+UPDATE {{.MetaSchema}}.moved_legacy_tables
+SET added_columns = array_append(added_columns, 'id')
+WHERE table_name = 'absurd_keywords'
+  AND NOT ('id' = ANY(added_columns))
+  AND NOT EXISTS (
+      SELECT 1 FROM information_schema.columns
+      WHERE table_schema = lower('{{.MetaSchema}}') AND table_name = 'absurd_keywords' AND column_name = 'id'
+  );
+ALTER TABLE {{.MetaSchema}}.absurd_keywords ADD COLUMN IF NOT EXISTS id BIGSERIAL;
+ALTER TABLE {{.MetaSchema}}.absurd_keywords ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS {{.MetaSchema}}.generation_log;
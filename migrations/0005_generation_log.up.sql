@@ -0,0 +1,19 @@
+-- Setiap SQL yang dihasilkan untuk /api/query dicatat bersama versi template prompt-nya
+-- agar akurasi antar versi prompt bisa dibandingkan.
+CREATE TABLE IF NOT EXISTS {{.MetaSchema}}.generation_log (
+    id               BIGSERIAL PRIMARY KEY,
+    prompt           TEXT NOT NULL,
+    generated_sql    TEXT,
+    prompt_version   TEXT,
+    provider         TEXT,
+    model            TEXT,
+    is_cached        BOOLEAN NOT NULL DEFAULT false,
+    exec_success     BOOLEAN,
+    row_count        INTEGER,
+    error            TEXT,
+    latency_ms       INTEGER,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS generation_log_prompt_version_idx
+    ON {{.MetaSchema}}.generation_log (prompt_version, created_at);
@@ -0,0 +1 @@
+ALTER TABLE {{.MetaSchema}}.feedback_submissions DROP COLUMN IF EXISTS test_rows_truncated;
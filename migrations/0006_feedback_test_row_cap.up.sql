@@ -0,0 +1,3 @@
+-- Uji eksekusi koreksi dibatasi jumlah barisnya; test_row_count menjadi batas bawah jika terpotong.
+ALTER TABLE {{.MetaSchema}}.feedback_submissions
+    ADD COLUMN IF NOT EXISTS test_rows_truncated BOOLEAN NOT NULL DEFAULT false;
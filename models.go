@@ -9,11 +9,39 @@ type QueryRequest struct {
 
 type PromptRequest struct {
 	Prompt string `json:"prompt"`
+	// PromptVersion memaksa versi template prompt tertentu (kosong = default/rollout)
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// Candidates meminta N kandidat SQL dengan voting hasil eksekusi (0 = SQL_CANDIDATES)
+	Candidates int `json:"candidates,omitempty"`
+	// Confirm menjalankan query meskipun confidence di bawah CONFIDENCE_THRESHOLD
+	Confirm bool `json:"confirm,omitempty"`
+	// Debug / Include meminta info provenance & timing (hanya untuk DEBUG_ALLOWED_ROLES)
+	Debug   bool     `json:"debug,omitempty"`
+	Include []string `json:"include,omitempty"`
+}
+
+// SQLGenOptions mengatur satu kali generasi SQL.
+type SQLGenOptions struct {
+	PromptVersion string
+	Candidates    int
+	// SkipCache melewati lookup semantic cache (dipakai eval agar yang diukur adalah model)
+	SkipCache bool
+	// Timings (opsional) diisi durasi per tahap pipeline
+	Timings StageTimings
+	// PromptVector (opsional) adalah embedding prompt yang sudah dihitung, mis. oleh ClassifyPrompt
+	PromptVector []float32
 }
 
 type FeedbackRequest struct {
-	PromptAsli string `json:"prompt_asli"`
-	SqlKoreksi string `json:"sql_koreksi"`
+	PromptAsli  string `json:"prompt_asli"`
+	SqlAsli     string `json:"sql_asli"` // SQL yang dihasilkan AI (opsional)
+	SqlKoreksi  string `json:"sql_koreksi"`
+	SubmittedBy string `json:"submitted_by"`
+}
+
+type FeedbackReviewRequest struct {
+	ReviewedBy string `json:"reviewed_by"`
+	Note       string `json:"note"`
 }
 
 type AISqlResponse struct {
@@ -23,6 +51,31 @@ type AISqlResponse struct {
 	IsCached    bool
 	IsAmbiguous bool
 	Suggestions []string
+	// Clarification diisi jika LLM meminta informasi tambahan alih-alih menulis SQL
+	Clarification *ClarificationRequest
+	// Field dari kontrak JSON LLM (kosong untuk cache hit)
+	Reasoning     string
+	TablesUsed    []string
+	LLMConfidence float64
+	Provider      string
+	Model         string
+	// PromptVersion adalah versi template prompt yang menghasilkan SQL ini
+	PromptVersion string
+	// Vote diisi jika SQL dipilih lewat voting multi-kandidat
+	Vote *VoteSummary
+	// Result adalah hasil eksekusi kandidat pemenang (nil jika belum/terpotong row cap)
+	Result *QueryResult
+	// Source: cache, rag atau zero_shot; CacheScore/RAGScore adalah skor kemiripan teratas
+	Source     string
+	CacheScore float32
+	RAGScore   float32
+	// Confidence diisi GetSQL untuk SQL yang tidak ambigu
+	Confidence *ConfidenceBreakdown
+	// Provenance untuk debug: contoh RAG yang dipertimbangkan dan total token LLM
+	RAGExamples []RAGExampleRef
+	Usage       llmUsage
+	// Degraded: Qdrant tidak tersedia, SQL dibuat tanpa semantic cache & RAG vektor
+	Degraded bool
 }
 
 type SqlExample struct {
@@ -36,11 +89,28 @@ type AppError struct {
 	Detail  string `json:"detail,omitempty"`
 }
 
+// ValidationError menandai input yang ditolak validasi (dipetakan ke 4xx, bukan error server).
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
 type QueryResponse struct {
-	Status      string      `json:"status"`
-	Message     string      `json:"message,omitempty"`
-	Data        interface{} `json:"data,omitempty"`
-	Suggestions []string    `json:"suggestions,omitempty"`
-	ErrorCode   string      `json:"error_code,omitempty"`
-	ErrorDetail string      `json:"error_detail,omitempty"`
+	Status           string               `json:"status"`
+	Message          string               `json:"message,omitempty"`
+	Data             interface{}          `json:"data,omitempty"`
+	Suggestions      []string             `json:"suggestions,omitempty"`
+	Questions        []string             `json:"questions,omitempty"`
+	Confidence       *float64             `json:"confidence,omitempty"`
+	Source           string               `json:"source,omitempty"`
+	ConfidenceDetail *ConfidenceBreakdown `json:"confidence_detail,omitempty"`
+	Interpretation   string               `json:"interpretation,omitempty"`
+	Voting           *VoteSummary         `json:"voting,omitempty"`
+	Debug            *QueryDebugInfo      `json:"debug,omitempty"`
+	Degraded         bool                 `json:"degraded,omitempty"`
+	ErrorCode        string               `json:"error_code,omitempty"`
+	ErrorDetail      string               `json:"error_detail,omitempty"`
 }
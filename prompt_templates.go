@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Template bawaan; file <versi>.tmpl di PROMPT_TEMPLATES_DIR menambah atau menimpa versi ini.
+//
+//go:embed prompts/*.tmpl
+var builtinPromptTemplates embed.FS
+
+var promptVersionRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// PromptTemplateData adalah variabel yang tersedia di template prompt generasi SQL.
+type PromptTemplateData struct {
+	Today                     string
+	DDL                       string
+	ReferenceData             string
+	BusinessDictionary        string
+	SQLContext                string
+	ClarificationEnabled      bool
+	ClarificationInstructions string
+	OutputFormat              string
+	Question                  string
+}
+
+type PromptTemplate struct {
+	Version  string `json:"version"`
+	Source   string `json:"source"` // "builtin" atau path file
+	Checksum string `json:"checksum"`
+	tmpl     *template.Template
+}
+
+// promptRolloutShare: persentase traffic yang diarahkan ke satu versi template.
+type promptRolloutShare struct {
+	Version string `json:"version"`
+	Percent int    `json:"percent"`
+}
+
+type promptRegistry struct {
+	templates map[string]*PromptTemplate
+	rollout   []promptRolloutShare
+}
+
+var (
+	promptRegistryMu  sync.RWMutex
+	promptRegistryCur *promptRegistry
+)
+
+func (t *PromptTemplate) Render(data PromptTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("gagal render template prompt %s: %w", t.Version, err)
+	}
+	return buf.String(), nil
+}
+
+func parsePromptTemplate(version, source string, body []byte) (*PromptTemplate, error) {
+	tmpl, err := template.New(version).Option("missingkey=error").Parse(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("template prompt %s (%s) tidak valid: %w", version, source, err)
+	}
+	sum := sha256.Sum256(body)
+	return &PromptTemplate{
+		Version:  version,
+		Source:   source,
+		Checksum: hex.EncodeToString(sum[:8]),
+		tmpl:     tmpl,
+	}, nil
+}
+
+func loadPromptTemplatesFrom(fsys fs.FS, source string, into map[string]*PromptTemplate) error {
+	paths, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		version := strings.TrimSuffix(p, ".tmpl")
+		if !promptVersionRe.MatchString(version) {
+			log.Printf("PERINGATAN: Nama template prompt '%s' diabaikan (versi tidak valid)", p)
+			continue
+		}
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		src := source
+		if src != "builtin" {
+			src = filepath.Join(source, p)
+		}
+		t, err := parsePromptTemplate(version, src, body)
+		if err != nil {
+			return err
+		}
+		into[version] = t
+	}
+	return nil
+}
+
+// parsePromptRollout membaca PROMPT_ROLLOUT, mis. "v2:10,v3:5" (sisa traffic ke versi default).
+func parsePromptRollout(raw string, templates map[string]*PromptTemplate) ([]promptRolloutShare, error) {
+	var shares []promptRolloutShare
+	total := 0
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		version, pctStr, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("PROMPT_ROLLOUT '%s' harus berformat versi:persen", part)
+		}
+		version = strings.TrimSpace(version)
+		pct, err := strconv.Atoi(strings.TrimSpace(pctStr))
+		if err != nil || pct < 0 || pct > 100 {
+			return nil, fmt.Errorf("persentase PROMPT_ROLLOUT untuk %s tidak valid: %s", version, pctStr)
+		}
+		if _, ok := templates[version]; !ok {
+			return nil, fmt.Errorf("PROMPT_ROLLOUT menyebut versi %s yang tidak ada", version)
+		}
+		total += pct
+		shares = append(shares, promptRolloutShare{Version: version, Percent: pct})
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("total persentase PROMPT_ROLLOUT %d%% melebihi 100%%", total)
+	}
+	return shares, nil
+}
+
+// ReloadPromptTemplates memuat ulang template bawaan + PROMPT_TEMPLATES_DIR dan konfigurasi rollout.
+func ReloadPromptTemplates() (*promptRegistry, error) {
+	if AppConfig == nil {
+		return nil, fmt.Errorf("konfigurasi aplikasi belum dimuat")
+	}
+
+	templates := make(map[string]*PromptTemplate)
+	sub, err := fs.Sub(builtinPromptTemplates, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPromptTemplatesFrom(sub, "builtin", templates); err != nil {
+		return nil, err
+	}
+
+	if dir := AppConfig.PromptTemplatesDir; dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if err := loadPromptTemplatesFrom(os.DirFS(dir), dir, templates); err != nil {
+				return nil, err
+			}
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("gagal membaca PROMPT_TEMPLATES_DIR: %w", err)
+		}
+	}
+
+	if _, ok := templates[AppConfig.PromptDefaultVersion]; !ok {
+		return nil, fmt.Errorf("template prompt default %s tidak ditemukan", AppConfig.PromptDefaultVersion)
+	}
+	rollout, err := parsePromptRollout(AppConfig.PromptRollout, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := &promptRegistry{templates: templates, rollout: rollout}
+	promptRegistryMu.Lock()
+	promptRegistryCur = reg
+	promptRegistryMu.Unlock()
+
+	log.Printf("✅ %d template prompt dimuat (default %s, rollout %d versi).", len(templates), AppConfig.PromptDefaultVersion, len(rollout))
+	return reg, nil
+}
+
+func currentPromptRegistry() (*promptRegistry, error) {
+	promptRegistryMu.RLock()
+	reg := promptRegistryCur
+	promptRegistryMu.RUnlock()
+	if reg != nil {
+		return reg, nil
+	}
+	return ReloadPromptTemplates()
+}
+
+// ListPromptTemplates mengembalikan semua versi template (urut nama) beserta rollout aktif.
+func ListPromptTemplates() ([]*PromptTemplate, []promptRolloutShare, error) {
+	reg, err := currentPromptRegistry()
+	if err != nil {
+		return nil, nil, err
+	}
+	list := make([]*PromptTemplate, 0, len(reg.templates))
+	for _, t := range reg.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, reg.rollout, nil
+}
+
+// SelectPromptTemplate memilih template: versi yang diminta eksplisit, atau lewat rollout.
+// Bucket rollout ditentukan dari hash prompt sehingga pertanyaan yang sama selalu
+// mendapat versi yang sama (hasil A/B tidak tercampur dengan semantic cache).
+func SelectPromptTemplate(requested, prompt string) (*PromptTemplate, error) {
+	reg, err := currentPromptRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	if requested != "" {
+		t, ok := reg.templates[requested]
+		if !ok {
+			return nil, &ValidationError{Reason: fmt.Sprintf("versi template prompt '%s' tidak ditemukan", requested)}
+		}
+		return t, nil
+	}
+
+	if len(reg.rollout) > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(normalizePromptKey(prompt)))
+		bucket := int(h.Sum32() % 100)
+		for _, share := range reg.rollout {
+			if bucket < share.Percent {
+				return reg.templates[share.Version], nil
+			}
+			bucket -= share.Percent
+		}
+	}
+	return reg.templates[AppConfig.PromptDefaultVersion], nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadBuiltinPromptTemplates(t *testing.T) map[string]*PromptTemplate {
+	t.Helper()
+	sub, err := fs.Sub(builtinPromptTemplates, "prompts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := make(map[string]*PromptTemplate)
+	if err := loadPromptTemplatesFrom(sub, "builtin", templates); err != nil {
+		t.Fatal(err)
+	}
+	return templates
+}
+
+func TestBuiltinPromptClarificationSwitch(t *testing.T) {
+	v1, ok := loadBuiltinPromptTemplates(t)["v1"]
+	if !ok {
+		t.Fatal("template bawaan v1 tidak ada")
+	}
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		wantPresent string
+		wantAbsent  string
+	}{
+		{"klarifikasi aktif", true, "== KAPAN MEMINTA KLARIFIKASI ==", "Tanpa Klarifikasi"},
+		{"klarifikasi mati", false, "Tanpa Klarifikasi", "== KAPAN MEMINTA KLARIFIKASI =="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := PromptTemplateData{ClarificationEnabled: tt.enabled, Question: "berapa nasabah aktif?"}
+			if tt.enabled {
+				data.ClarificationInstructions = "\n== KAPAN MEMINTA KLARIFIKASI ==\n"
+			}
+			out, err := v1.Render(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, tt.wantPresent) {
+				t.Errorf("prompt tidak berisi %q", tt.wantPresent)
+			}
+			if strings.Contains(out, tt.wantAbsent) {
+				t.Errorf("prompt tidak boleh berisi %q", tt.wantAbsent)
+			}
+			if !strings.Contains(out, `Pertanyaan Pengguna: "berapa nasabah aktif?"`) {
+				t.Error("pertanyaan tidak ter-render")
+			}
+		})
+	}
+}
+
+func TestParsePromptRollout(t *testing.T) {
+	templates := map[string]*PromptTemplate{"v1": {}, "v2": {}, "v3": {}}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []promptRolloutShare
+		wantErr bool
+	}{
+		{"kosong", "", nil, false},
+		{"satu versi", "v2:10", []promptRolloutShare{{Version: "v2", Percent: 10}}, false},
+		{"beberapa versi dengan spasi", " v2 : 10 , v3:5,", []promptRolloutShare{{Version: "v2", Percent: 10}, {Version: "v3", Percent: 5}}, false},
+		{"tepat 100", "v2:60,v3:40", []promptRolloutShare{{Version: "v2", Percent: 60}, {Version: "v3", Percent: 40}}, false},
+		{"tanpa persen", "v2", nil, true},
+		{"persen bukan angka", "v2:abc", nil, true},
+		{"persen negatif", "v2:-1", nil, true},
+		{"versi tidak ada", "v9:10", nil, true},
+		{"total melebihi 100", "v2:60,v3:50", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePromptRollout(tt.raw, templates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePromptRollout(%q) error = %v, wantErr %t", tt.raw, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePromptRollout(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bagian informasi debug yang bisa diminta lewat include.
+const (
+	debugIncludeSQL     = "sql"
+	debugIncludeCache   = "cache"
+	debugIncludeRAG     = "rag"
+	debugIncludeLLM     = "llm"
+	debugIncludeTimings = "timings"
+)
+
+var allDebugIncludes = []string{debugIncludeSQL, debugIncludeCache, debugIncludeRAG, debugIncludeLLM, debugIncludeTimings}
+
+// StageTimings mencatat durasi per tahap pipeline dalam milidetik.
+type StageTimings map[string]float64
+
+func (t StageTimings) observe(stage string, start time.Time) {
+	if t == nil {
+		return
+	}
+	ms := float64(time.Since(start).Microseconds()) / 1000
+	t[stage] += math.Round(ms*10) / 10
+}
+
+// RAGExampleRef adalah contoh RAG yang dipertimbangkan untuk prompt beserta skornya.
+type RAGExampleRef struct {
+	ID          string  `json:"id,omitempty"`
+	DenseScore  float32 `json:"dense_score,omitempty"`
+	SparseScore float64 `json:"sparse_score,omitempty"`
+	FusedScore  float64 `json:"fused_score,omitempty"`
+	Used        bool    `json:"used"`
+}
+
+// QueryDebugInfo adalah provenance & timing yang dikembalikan /api/query jika diminta
+// oleh role yang diizinkan (DEBUG_ALLOWED_ROLES).
+type QueryDebugInfo struct {
+	SQL           string          `json:"sql,omitempty"`
+	PromptVersion string          `json:"prompt_version,omitempty"`
+	IsCached      *bool           `json:"is_cached,omitempty"`
+	CacheScore    *float32        `json:"cache_score,omitempty"`
+	RAGExamples   []RAGExampleRef `json:"rag_examples,omitempty"`
+	Provider      string          `json:"provider,omitempty"`
+	Model         string          `json:"model,omitempty"`
+	Usage         *llmUsage       `json:"usage,omitempty"`
+	TimingsMs     StageTimings    `json:"timings_ms,omitempty"`
+}
+
+func ragExampleRefs(candidates []ragCandidate, used bool) []RAGExampleRef {
+	refs := make([]RAGExampleRef, 0, len(candidates))
+	for _, c := range candidates {
+		refs = append(refs, RAGExampleRef{
+			ID:          c.ID,
+			DenseScore:  c.DenseScore,
+			SparseScore: c.SparseScore,
+			FusedScore:  c.FusedScore,
+			Used:        used,
+		})
+	}
+	return refs
+}
+
+// requestedDebugIncludes membaca ?debug=true / ?include=sql,timings atau field body yang sama.
+// "all" atau debug=true berarti semua bagian.
+func requestedDebugIncludes(r *http.Request, bodyDebug bool, bodyInclude []string) map[string]bool {
+	include := make(map[string]bool)
+	debug := bodyDebug
+	if v := r.URL.Query().Get("debug"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil && b {
+			debug = true
+		}
+	}
+
+	items := append([]string(nil), bodyInclude...)
+	if v := r.URL.Query().Get("include"); v != "" {
+		items = append(items, strings.Split(v, ",")...)
+	}
+	for _, it := range items {
+		it = strings.ToLower(strings.TrimSpace(it))
+		if it == "all" {
+			debug = true
+			continue
+		}
+		for _, known := range allDebugIncludes {
+			if it == known {
+				include[it] = true
+			}
+		}
+	}
+	if debug {
+		for _, known := range allDebugIncludes {
+			include[known] = true
+		}
+	}
+	return include
+}
+
+// debugTokenHeader membawa DEBUG_TOKEN dari client yang boleh melihat info debug.
+const debugTokenHeader = "X-Debug-Token"
+
+// debugAllowed memeriksa role dari header DEBUG_ROLE_HEADER terhadap DEBUG_ALLOWED_ROLES
+// (default kosong = debug mati). Header role dikirim client, jadi hanya bisa dipercaya jika
+// gateway di depan service menimpanya; jika DEBUG_TOKEN diisi, header X-Debug-Token juga wajib cocok.
+func debugAllowed(r *http.Request) bool {
+	if AppConfig == nil || len(AppConfig.DebugAllowedRoles) == 0 {
+		return false
+	}
+	if AppConfig.DebugToken != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(debugTokenHeader)), []byte(AppConfig.DebugToken)) != 1 {
+		return false
+	}
+	for _, role := range strings.Split(r.Header.Get(AppConfig.DebugRoleHeader), ",") {
+		role = strings.ToLower(strings.TrimSpace(role))
+		for _, allowed := range AppConfig.DebugAllowedRoles {
+			if role != "" && role == strings.ToLower(allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildQueryDebug menyusun bagian debug yang diminta dari hasil pipeline.
+func buildQueryDebug(include map[string]bool, aiResp AISqlResponse, timings StageTimings) *QueryDebugInfo {
+	if len(include) == 0 {
+		return nil
+	}
+	d := &QueryDebugInfo{}
+	if include[debugIncludeSQL] {
+		d.SQL = aiResp.SQL
+		d.PromptVersion = aiResp.PromptVersion
+	}
+	if include[debugIncludeCache] {
+		isCached := aiResp.IsCached
+		d.IsCached = &isCached
+		if aiResp.CacheScore > 0 {
+			cacheScore := aiResp.CacheScore
+			d.CacheScore = &cacheScore
+		}
+	}
+	if include[debugIncludeRAG] {
+		d.RAGExamples = aiResp.RAGExamples
+	}
+	if include[debugIncludeLLM] && !aiResp.IsCached {
+		d.Provider = aiResp.Provider
+		d.Model = aiResp.Model
+		usage := aiResp.Usage
+		d.Usage = &usage
+	}
+	if include[debugIncludeTimings] {
+		d.TimingsMs = timings
+	}
+	return d
+}
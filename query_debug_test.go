@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDebugAllowed(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+
+	tests := []struct {
+		name   string
+		roles  []string
+		token  string
+		header map[string]string
+		want   bool
+	}{
+		{"default kosong mematikan debug", nil, "", map[string]string{"X-User-Role": "admin"}, false},
+		{"role diizinkan", []string{"admin"}, "", map[string]string{"X-User-Role": "admin"}, true},
+		{"role tidak peka huruf dan dipisah koma", []string{"Admin"}, "", map[string]string{"X-User-Role": "teller, ADMIN"}, true},
+		{"role lain ditolak", []string{"admin"}, "", map[string]string{"X-User-Role": "teller"}, false},
+		{"tanpa header role", []string{"admin"}, "", nil, false},
+		{"token wajib jika diisi", []string{"admin"}, "rahasia", map[string]string{"X-User-Role": "admin"}, false},
+		{"token salah", []string{"admin"}, "rahasia", map[string]string{"X-User-Role": "admin", debugTokenHeader: "salah"}, false},
+		{"token dan role cocok", []string{"admin"}, "rahasia", map[string]string{"X-User-Role": "admin", debugTokenHeader: "rahasia"}, true},
+		{"token cocok tanpa role", []string{"admin"}, "rahasia", map[string]string{debugTokenHeader: "rahasia"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = &Config{DebugRoleHeader: "X-User-Role", DebugAllowedRoles: tt.roles, DebugToken: tt.token}
+			r := httptest.NewRequest("POST", "/api/query", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			if got := debugAllowed(r); got != tt.want {
+				t.Errorf("debugAllowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestedDebugIncludes(t *testing.T) {
+	all := map[string]bool{}
+	for _, k := range allDebugIncludes {
+		all[k] = true
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		bodyDebug   bool
+		bodyInclude []string
+		want        map[string]bool
+	}{
+		{"tanpa permintaan", "/api/query", false, nil, map[string]bool{}},
+		{"query debug=true", "/api/query?debug=true", false, nil, all},
+		{"query debug tidak valid diabaikan", "/api/query?debug=ya", false, nil, map[string]bool{}},
+		{"body debug", "/api/query", true, nil, all},
+		{"include query dan body digabung", "/api/query?include=SQL,%20timings", false, []string{"rag"},
+			map[string]bool{debugIncludeSQL: true, debugIncludeTimings: true, debugIncludeRAG: true}},
+		{"include tidak dikenal dibuang", "/api/query?include=sql,password", false, nil, map[string]bool{debugIncludeSQL: true}},
+		{"include all", "/api/query", false, []string{"all"}, all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.url, nil)
+			if got := requestedDebugIncludes(r, tt.bodyDebug, tt.bodyInclude); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requestedDebugIncludes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
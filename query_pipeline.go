@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Status QueryResponse selain "error".
+const (
+	queryStatusSuccess              = "success"
+	queryStatusAmbiguous            = "ambiguous"
+	queryStatusConfirmationRequired = "confirmation_required"
+)
+
+// QueryError adalah kegagalan pipeline query beserta status HTTP dan kode error yang dikirim API.
+// Stage diisi jika pipeline berhenti karena context request selesai (deadline habis/dibatalkan).
+type QueryError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Detail     string
+	Stage      string
+}
+
+func (e *QueryError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s: %s", e.Message, e.Detail)
+	}
+	return e.Message
+}
+
+func requestContextQueryError(ctx context.Context, stage string) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return &QueryError{
+		StatusCode: http.StatusGatewayTimeout,
+		Code:       "REQUEST_TIMEOUT",
+		Message:    "Permintaan melebihi batas waktu pemrosesan",
+		Detail:     ctx.Err().Error(),
+		Stage:      stage,
+	}
+}
+
+// RunDynamicQuery menjalankan pipeline /api/query lengkap: klasifikasi prompt, generate SQL,
+// gerbang confidence (kecuali req.Confirm), eksekusi dan penyimpanan cache. Dipakai bersama
+// oleh HandleDynamicQuery dan perintah CLI `ask` agar keduanya berperilaku sama.
+// Status response adalah success, ambiguous atau confirmation_required; kegagalan dikembalikan
+// sebagai *QueryError.
+func RunDynamicQuery(ctx context.Context, req PromptRequest, include map[string]bool) (QueryResponse, error) {
+	normalizedPrompt := strings.ToLower(strings.TrimSpace(req.Prompt))
+	if normalizedPrompt == "" {
+		return QueryResponse{}, &QueryError{StatusCode: http.StatusBadRequest, Code: "EMPTY_PROMPT", Message: "Prompt tidak boleh kosong"}
+	}
+	log.Printf("Menerima Prompt (Normalized): %s", normalizedPrompt)
+
+	requestStart := time.Now()
+	timings := StageTimings{}
+
+	stageStart := time.Now()
+	stageCtx, cancelStage := stageContext(ctx, budgetShareClassify)
+	verdict, err := ClassifyPrompt(stageCtx, normalizedPrompt)
+	cancelStage()
+	timings.observe("classify", stageStart)
+	if ctxErr := requestContextQueryError(ctx, "klasifikasi prompt"); ctxErr != nil {
+		return QueryResponse{}, ctxErr
+	}
+	if err != nil {
+		log.Printf("Error cek absurd: %v", err)
+		return QueryResponse{}, &QueryError{StatusCode: http.StatusInternalServerError, Code: "INTERNAL_ERROR", Message: "Layanan sedang bermasalah"}
+	}
+	if verdict.Absurd {
+		return QueryResponse{
+			Status:      queryStatusAmbiguous,
+			Message:     "Pertanyaan kurang jelas",
+			Suggestions: verdict.Suggestions,
+		}, nil
+	}
+	if err := validateDangerousIntent(normalizedPrompt); err != nil {
+		log.Printf("SECURITY BLOCK: %v", err)
+		return QueryResponse{}, &QueryError{StatusCode: http.StatusForbidden, Code: "DANGEROUS_INTENT", Message: err.Error()}
+	}
+
+	genStart := time.Now()
+	aiResp, err := GetSQL(ctx, normalizedPrompt, SQLGenOptions{
+		PromptVersion: strings.TrimSpace(req.PromptVersion),
+		Candidates:    req.Candidates,
+		Timings:       timings,
+		PromptVector:  verdict.Vector,
+	})
+	if ctxErr := requestContextQueryError(ctx, "generate SQL"); ctxErr != nil {
+		return QueryResponse{}, ctxErr
+	}
+	if err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			return QueryResponse{}, &QueryError{StatusCode: http.StatusUnprocessableEntity, Code: "INVALID_PROMPT_VERSION", Message: validationErr.Reason}
+		}
+		log.Printf("AI gagal generate SQL: %v", err)
+		return QueryResponse{}, &QueryError{StatusCode: http.StatusInternalServerError, Code: "AI_GENERATION_FAILED", Message: "Gagal menghasilkan query SQL"}
+	}
+	genEntry := newGenerationLogEntry(normalizedPrompt, aiResp)
+	genEntry.Latency = time.Since(genStart)
+
+	if aiResp.IsAmbiguous {
+		resp := QueryResponse{
+			Status:      queryStatusAmbiguous,
+			Message:     "Maaf, pertanyaan Anda kurang jelas atau tidak cukup spesifik",
+			Suggestions: aiResp.Suggestions,
+		}
+		if c := aiResp.Clarification; c != nil {
+			resp.Questions = c.Questions
+			if c.Reason != "" {
+				resp.Message = c.Reason
+			}
+		}
+		return resp, nil
+	}
+	if strings.TrimSpace(aiResp.SQL) == "" {
+		return QueryResponse{}, &QueryError{StatusCode: http.StatusUnprocessableEntity, Code: "EMPTY_SQL", Message: "AI tidak menghasilkan query SQL yang valid"}
+	}
+
+	var score *float64
+	if aiResp.Confidence != nil {
+		score = &aiResp.Confidence.Score
+	}
+	if aiResp.Confidence.IsLowConfidence() && !req.Confirm {
+		log.Printf("Confidence rendah (%.3f < %.3f), meminta konfirmasi user.", aiResp.Confidence.Score, AppConfig.ConfidenceThreshold)
+		RecordGeneration(genEntry)
+		timings.observe("total", requestStart)
+		return QueryResponse{
+			Status: queryStatusConfirmationRequired,
+			Message: fmt.Sprintf("Tingkat keyakinan jawaban rendah (%.2f). Periksa interpretasi berikut, lalu kirim ulang dengan \"confirm\": true untuk tetap menjalankan query.",
+				aiResp.Confidence.Score),
+			Confidence:       score,
+			Source:           aiResp.Source,
+			ConfidenceDetail: aiResp.Confidence,
+			Interpretation:   aiResp.Reasoning,
+			Suggestions:      NearestExamplePrompts(ctx, normalizedPrompt, aiResp.Vector, AppConfig.SuggestionCount),
+			Voting:           aiResp.Vote,
+			Debug:            buildQueryDebug(include, aiResp, timings),
+			Degraded:         aiResp.Degraded,
+		}, nil
+	}
+
+	log.Printf("SQL yang akan dieksekusi: %s", aiResp.SQL)
+	var data QueryResult
+	var execErr error
+	if aiResp.Result != nil {
+		data = *aiResp.Result
+	} else {
+		stageStart = time.Now()
+		stageCtx, cancelStage = stageContext(ctx, budgetShareExecute)
+		data, execErr = ExecuteDynamicQuery(stageCtx, aiResp.SQL, nil)
+		cancelStage()
+		timings.observe("execute", stageStart)
+	}
+	if ctxErr := requestContextQueryError(ctx, "eksekusi query"); ctxErr != nil {
+		return QueryResponse{}, ctxErr
+	}
+	execOK := execErr == nil
+	genEntry.ExecSuccess = &execOK
+	if execErr != nil {
+		genEntry.Error = execErr.Error()
+	} else {
+		rowCount := len(data.Rows)
+		genEntry.RowCount = &rowCount
+	}
+	RecordGeneration(genEntry)
+	if execErr != nil {
+		log.Printf("GAGAL EKSEKUSI QUERY: %v | SQL: %s", execErr, aiResp.SQL)
+		return QueryResponse{}, &QueryError{
+			StatusCode: http.StatusUnprocessableEntity,
+			Code:       "QUERY_EXECUTION_FAILED",
+			Message:    "Query tidak dapat dieksekusi. Mungkin syntax salah atau melanggar aturan database",
+			Detail:     execErr.Error(),
+		}
+	}
+
+	// SQL yang hanya dijalankan karena dikonfirmasi user tidak disimpan ke cache
+	if !aiResp.IsCached && !aiResp.Confidence.IsLowConfidence() {
+		go SaveToCache(aiResp.PromptAsli, aiResp.Vector, aiResp.SQL, aiResp.PromptVersion)
+	}
+
+	timings.observe("total", requestStart)
+	return QueryResponse{
+		Status:           queryStatusSuccess,
+		Message:          "Query berhasil dieksekusi",
+		Data:             data,
+		Confidence:       score,
+		Source:           aiResp.Source,
+		ConfidenceDetail: aiResp.Confidence,
+		Voting:           aiResp.Vote,
+		Debug:            buildQueryDebug(include, aiResp, timings),
+		Degraded:         aiResp.Degraded,
+	}, nil
+}
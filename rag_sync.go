@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ragItem adalah satu dokumen yang seharusnya ada di collection RAG (DDL atau contoh SQL).
+type ragItem struct {
+	EmbedText string
+	Payload   map[string]interface{}
+	Hash      string
+}
+
+// pointID deterministik dari content hash, sehingga item yang tidak berubah punya ID yang sama.
+func (it ragItem) pointID() string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(it.Hash)).String()
+}
+
+func newRagItem(category, embedText, content string, payload map[string]interface{}) ragItem {
+	sum := sha256.Sum256([]byte(category + "\x00" + embedText + "\x00" + content))
+	hash := hex.EncodeToString(sum[:])
+
+	payload["content"] = content
+	payload["category"] = category
+	payload["content_hash"] = hash
+	return ragItem{EmbedText: embedText, Payload: payload, Hash: hash}
+}
+
+// cleanExamplePrompt membuang prefix "-- Pertanyaan:" dan tanda kutip dari prompt contoh.
+func cleanExamplePrompt(prompt string) string {
+	cleanPrompt := strings.Replace(prompt, "-- Pertanyaan: ", "", 1)
+	cleanPrompt = strings.Replace(cleanPrompt, "\"", "", -1)
+	return strings.TrimSpace(cleanPrompt)
+}
+
+// buildRagItems menyusun daftar item RAG yang diinginkan dari DDL snapshot & rag_sql_examples.
+func buildRagItems(snap *SchemaSnapshot, examples []SqlExample) []ragItem {
+	items := make([]ragItem, 0, len(snap.DDLs)+len(snap.Dictionary)+len(examples))
+	for _, ddl := range snap.DDLs {
+		items = append(items, newRagItem("ddl", ddl, ddl, map[string]interface{}{
+			"table_name":     ddlTableName(ddl),
+			"schema_version": snap.Version,
+		}))
+	}
+	for _, d := range snap.Dictionary {
+		items = append(items, dictionaryRagItem(d))
+	}
+	for _, ex := range examples {
+		items = append(items, sqlExampleRagItem(ex))
+	}
+	return items
+}
+
+func dictionaryRagItem(d DictionaryItem) ragItem {
+	return newRagItem("dictionary", d.Istilah+": "+d.Definisi, dictionaryLine(d), map[string]interface{}{
+		"dictionary_id": d.ID,
+		"istilah":       d.Istilah,
+	})
+}
+
+func sqlExampleRagItem(ex SqlExample) ragItem {
+	cleanPrompt := cleanExamplePrompt(ex.PromptOnly)
+	return newRagItem("sql", cleanPrompt, ex.FullContent, map[string]interface{}{
+		"prompt_preview": cleanPrompt,
+	})
+}
+
+// UpsertSqlExampleToRAG langsung meng-embed satu contoh SQL ke collection RAG aktif (lewat alias)
+// tanpa retrain penuh. Jika previous diisi, point contoh lama dihapus.
+func UpsertSqlExampleToRAG(ctx context.Context, ex SqlExample, previous *SqlExample) error {
+	item := sqlExampleRagItem(ex)
+	var prev *ragItem
+	if previous != nil {
+		oldItem := sqlExampleRagItem(*previous)
+		prev = &oldItem
+	}
+	return syncRAGItem(ctx, &item, prev)
+}
+
+// syncRAGItem meng-upsert item (jika tidak nil) dan menghapus point previous (jika berbeda)
+// pada collection RAG aktif. Point ID sama dengan yang dihasilkan training, sehingga retrain
+// inkremental berikutnya memakai ulang vektornya.
+func syncRAGItem(ctx context.Context, item, previous *ragItem) error {
+	var change ragChange
+	if item != nil {
+		vector, err := GenerateEmbedding(ctx, item.EmbedText)
+		if err != nil {
+			return fmt.Errorf("gagal embed item RAG: %w", err)
+		}
+		change.upsert = &qdrantPoint{ID: item.pointID(), Vector: vector, Payload: item.Payload}
+	}
+	if previous != nil && (item == nil || previous.Hash != item.Hash) {
+		change.deleteID = previous.pointID()
+	}
+	return applyRAGChange(ctx, change)
+}
+
+// ragChange adalah perubahan langsung pada collection RAG (upsert dan/atau hapus satu point).
+type ragChange struct {
+	upsert   *qdrantPoint
+	deleteID string
+}
+
+var (
+	// ragLiveMu menyerialkan perubahan langsung dengan pemindahan alias oleh SyncRAGCollection,
+	// sehingga tidak ada perubahan yang mendarat di collection lama setelah shadow diselesaikan.
+	ragLiveMu sync.Mutex
+	// ragShadowChanges mencatat perubahan langsung per collection bayangan yang sedang dibangun;
+	// item shadow dibaca di awal job sehingga perubahan ini diterapkan ulang sebelum swap alias.
+	ragShadowChanges = map[string][]ragChange{}
+)
+
+// applyRAGChange mencatat perubahan untuk setiap shadow yang sedang dibangun lalu menerapkannya
+// ke collection aktif (lewat alias). Perubahan tetap masuk ke shadow walau penulisan ke
+// collection aktif gagal.
+func applyRAGChange(ctx context.Context, change ragChange) error {
+	ragLiveMu.Lock()
+	defer ragLiveMu.Unlock()
+	for shadow := range ragShadowChanges {
+		ragShadowChanges[shadow] = append(ragShadowChanges[shadow], change)
+	}
+	return writeRAGChange(ctx, AppConfig.QdrantCollectionName, change)
+}
+
+func writeRAGChange(ctx context.Context, collection string, change ragChange) error {
+	if change.upsert != nil {
+		if err := qdrantUpsertPoints(ctx, AppConfig.QdrantURL, collection, []qdrantPoint{*change.upsert}); err != nil {
+			return fmt.Errorf("gagal upsert item RAG ke '%s': %w", collection, err)
+		}
+	}
+	if change.deleteID != "" {
+		if err := qdrantDeletePoints(ctx, AppConfig.QdrantURL, collection, []string{change.deleteID}); err != nil {
+			return fmt.Errorf("gagal menghapus item RAG lama dari '%s': %w", collection, err)
+		}
+	}
+	return nil
+}
+
+var (
+	ragReadOverrideMu sync.RWMutex
+	ragReadOverride   string
+)
+
+// ragReadCollection adalah nama collection yang dipakai untuk membaca RAG: alias
+// QDRANT_COLLECTION_NAME, kecuali selama migrasi collection lama ke alias.
+func ragReadCollection() string {
+	ragReadOverrideMu.RLock()
+	defer ragReadOverrideMu.RUnlock()
+	if ragReadOverride != "" {
+		return ragReadOverride
+	}
+	return AppConfig.QdrantCollectionName
+}
+
+func setRAGReadOverride(collection string) {
+	ragReadOverrideMu.Lock()
+	ragReadOverride = collection
+	ragReadOverrideMu.Unlock()
+}
+
+// shadowCollectionName membuat nama collection bayangan yang unik walau dua sinkronisasi
+// berjalan pada detik yang sama: <alias>_<unix>_<8 karakter acak>.
+func shadowCollectionName(alias string, now time.Time) string {
+	return fmt.Sprintf("%s_%d_%s", alias, now.Unix(), strings.ReplaceAll(uuid.NewString(), "-", "")[:8])
+}
+
+type TrainOptions struct {
+	// Incremental memakai ulang vektor item yang content hash-nya tidak berubah.
+	Incremental bool
+	// Progress (opsional) dipanggil setiap kali satu item selesai diproses.
+	Progress func(TrainResult)
+}
+
+type TrainResult struct {
+	Collection string `json:"collection"`
+	Total      int    `json:"total"`
+	Embedded   int    `json:"embedded"`
+	Skipped    int    `json:"skipped"`
+	Failed     int    `json:"failed"`
+	Removed    int    `json:"removed"`
+}
+
+type embedFunc func(ctx context.Context, text string) ([]float32, error)
+
+const ragUpsertBatchSize = 64
+
+// SyncRAGCollection membangun collection bayangan (shadow) berisi items, lalu memindahkan alias
+// AppConfig.QdrantCollectionName ke collection tersebut. Selama proses, query tetap memakai
+// collection lama sehingga tidak ada jeda tanpa konteks RAG.
+func SyncRAGCollection(ctx context.Context, embed embedFunc, items []ragItem, opts TrainOptions) (TrainResult, error) {
+	alias := AppConfig.QdrantCollectionName
+	baseURL := AppConfig.QdrantURL
+	result := TrainResult{Total: len(items)}
+
+	liveCollection, err := qdrantResolveAlias(ctx, baseURL, alias)
+	if err != nil {
+		return result, fmt.Errorf("gagal membaca alias '%s': %w", alias, err)
+	}
+
+	// Instalasi lama: collection fisik bernama sama dengan alias, perlu dimigrasi sekali.
+	legacyCollection := false
+	sourceCollection := liveCollection
+	if liveCollection == "" {
+		exists, err := qdrantCollectionExists(ctx, baseURL, alias)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			legacyCollection = true
+			sourceCollection = alias
+		}
+	}
+
+	existingVectors := make(map[string][]float32)
+	if sourceCollection != "" {
+		points, err := qdrantScrollAllPoints(ctx, baseURL, sourceCollection)
+		if err != nil {
+			return result, fmt.Errorf("gagal membaca collection aktif '%s': %w", sourceCollection, err)
+		}
+		for _, p := range points {
+			existingVectors[p.ID] = p.Vector
+		}
+	}
+
+	shadow := shadowCollectionName(alias, time.Now())
+	log.Printf("🆕 Membuat collection bayangan '%s'...", shadow)
+	if err := qdrantCreateCollection(ctx, baseURL, shadow, AppConfig.EmbeddingVectorSize, AppConfig.QdrantDistanceMetric); err != nil {
+		return result, fmt.Errorf("gagal membuat collection bayangan: %w", err)
+	}
+
+	cleanupShadow := func() {
+		if err := qdrantDeleteCollection(context.Background(), baseURL, shadow); err != nil {
+			log.Printf("PERINGATAN: Gagal membersihkan collection bayangan '%s': %v", shadow, err)
+		}
+	}
+
+	ragLiveMu.Lock()
+	ragShadowChanges[shadow] = nil
+	ragLiveMu.Unlock()
+	defer func() {
+		ragLiveMu.Lock()
+		delete(ragShadowChanges, shadow)
+		ragLiveMu.Unlock()
+	}()
+
+	desired := make(map[string]bool, len(items))
+	var batch []qdrantPoint
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := qdrantUpsertPoints(ctx, baseURL, shadow, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			cleanupShadow()
+			return result, err
+		}
+
+		id := item.pointID()
+		desired[id] = true
+
+		vector, reused := existingVectors[id]
+		if opts.Incremental && reused && len(vector) > 0 {
+			result.Skipped++
+		} else {
+			vector, err = embed(ctx, item.EmbedText)
+			if err != nil {
+				if ctx.Err() != nil {
+					cleanupShadow()
+					return result, ctx.Err()
+				}
+				log.Printf("Skip item #%d (%s): %v", i, item.Payload["category"], err)
+				result.Failed++
+				if opts.Progress != nil {
+					opts.Progress(result)
+				}
+				continue
+			}
+			result.Embedded++
+		}
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+
+		batch = append(batch, qdrantPoint{ID: id, Vector: vector, Payload: item.Payload})
+		if len(batch) >= ragUpsertBatchSize {
+			if err := flush(); err != nil {
+				cleanupShadow()
+				return result, fmt.Errorf("gagal menyimpan vektor ke Qdrant: %w", err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		cleanupShadow()
+		return result, fmt.Errorf("gagal menyimpan vektor ke Qdrant: %w", err)
+	}
+
+	for id := range existingVectors {
+		if !desired[id] {
+			result.Removed++
+		}
+	}
+
+	if err := promoteRAGShadow(ctx, baseURL, alias, shadow, liveCollection != "", legacyCollection, cleanupShadow); err != nil {
+		return result, err
+	}
+	log.Printf("✅ Alias '%s' kini menunjuk ke '%s'.", alias, shadow)
+
+	if liveCollection != "" && liveCollection != shadow {
+		if err := qdrantDeleteCollection(ctx, baseURL, liveCollection); err != nil {
+			log.Printf("PERINGATAN: Gagal menghapus collection lama '%s': %v", liveCollection, err)
+		}
+	}
+
+	result.Collection = shadow
+	return result, nil
+}
+
+// promoteRAGShadow menerapkan perubahan langsung yang masuk selama build ke shadow, lalu
+// memindahkan alias ke shadow. ragLiveMu ditahan sepanjang proses sehingga perubahan berikutnya
+// menunggu dan langsung mendarat di shadow lewat alias.
+func promoteRAGShadow(ctx context.Context, baseURL, alias, shadow string, aliasExists, legacyCollection bool, cleanupShadow func()) error {
+	ragLiveMu.Lock()
+	defer ragLiveMu.Unlock()
+
+	if changes := ragShadowChanges[shadow]; len(changes) > 0 {
+		log.Printf("Menerapkan %d perubahan RAG selama retrain ke '%s'.", len(changes), shadow)
+		for _, change := range changes {
+			if err := writeRAGChange(ctx, shadow, change); err != nil {
+				cleanupShadow()
+				return err
+			}
+		}
+	}
+
+	if legacyCollection {
+		// Qdrant menolak alias yang namanya sama dengan collection yang masih ada, jadi collection
+		// lama harus dihapus sebelum alias dibuat. Selama jeda itu pembacaan RAG di proses ini
+		// diarahkan langsung ke shadow, dan collection lama baru dihapus setelah shadow siap dibaca.
+		log.Printf("Migrasi: collection lama '%s' diganti alias ke '%s'.", alias, shadow)
+		setRAGReadOverride(shadow)
+		defer setRAGReadOverride("")
+		if err := qdrantDeleteCollection(ctx, baseURL, alias); err != nil {
+			cleanupShadow()
+			return fmt.Errorf("gagal menghapus collection lama: %w", err)
+		}
+	}
+
+	if err := qdrantSwapAlias(ctx, baseURL, alias, shadow, aliasExists); err != nil {
+		if legacyCollection {
+			// Collection lama sudah terhapus: shadow dipertahankan sebagai satu-satunya salinan data.
+			return fmt.Errorf("gagal membuat alias '%s' ke '%s' (collection '%s' dipertahankan, buat alias manual atau jalankan ulang train): %w", alias, shadow, shadow, err)
+		}
+		cleanupShadow()
+		return fmt.Errorf("gagal memindahkan alias '%s': %w", alias, err)
+	}
+	return nil
+}
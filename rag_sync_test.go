@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShadowCollectionNameUniqueWithinSameSecond(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		name := shadowCollectionName("bpr_supra_rag", now)
+		if !strings.HasPrefix(name, "bpr_supra_rag_1700000000_") {
+			t.Fatalf("nama shadow %q tidak diawali alias dan timestamp", name)
+		}
+		if seen[name] {
+			t.Fatalf("nama shadow %q terpakai dua kali", name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestRAGReadCollectionOverride(t *testing.T) {
+	prev := AppConfig
+	AppConfig = &Config{QdrantCollectionName: "rag"}
+	defer func() { AppConfig = prev }()
+
+	if got := ragReadCollection(); got != "rag" {
+		t.Errorf("tanpa override = %q, want %q", got, "rag")
+	}
+	setRAGReadOverride("rag_shadow")
+	if got := ragReadCollection(); got != "rag_shadow" {
+		t.Errorf("dengan override = %q, want %q", got, "rag_shadow")
+	}
+	setRAGReadOverride("")
+	if got := ragReadCollection(); got != "rag" {
+		t.Errorf("setelah override dihapus = %q, want %q", got, "rag")
+	}
+}
+
+// fakeQdrant adalah Qdrant REST minimal (collection, alias, upsert/delete/scroll point) untuk
+// menguji SyncRAGCollection tanpa server sungguhan.
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]map[string]qdrantPoint
+	aliases     map[string]string
+}
+
+func newFakeQdrant() *fakeQdrant {
+	return &fakeQdrant{collections: map[string]map[string]qdrantPoint{}, aliases: map[string]string{}}
+}
+
+func (f *fakeQdrant) resolve(name string) string {
+	if c, ok := f.aliases[name]; ok {
+		return c
+	}
+	return name
+}
+
+func (f *fakeQdrant) pointIDs(collection string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for id := range f.collections[collection] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	reply := func(v any) { json.NewEncoder(w).Encode(map[string]any{"result": v, "status": "ok"}) }
+
+	if len(parts) == 2 && parts[1] == "aliases" {
+		if r.Method == http.MethodGet {
+			var list []qdrantAliasDescription
+			for a, c := range f.aliases {
+				list = append(list, qdrantAliasDescription{AliasName: a, CollectionName: c})
+			}
+			reply(map[string]any{"aliases": list})
+			return
+		}
+		var req struct {
+			Actions []map[string]struct {
+				AliasName      string `json:"alias_name"`
+				CollectionName string `json:"collection_name"`
+			} `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, action := range req.Actions {
+			if a, ok := action["delete_alias"]; ok {
+				delete(f.aliases, a.AliasName)
+			}
+			if a, ok := action["create_alias"]; ok {
+				f.aliases[a.AliasName] = a.CollectionName
+			}
+		}
+		reply(true)
+		return
+	}
+
+	name := f.resolve(parts[1])
+	points, exists := f.collections[name]
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			reply(map[string]any{"points_count": len(points)})
+		case http.MethodPut:
+			f.collections[name] = map[string]qdrantPoint{}
+			reply(true)
+		case http.MethodDelete:
+			delete(f.collections, name)
+			reply(true)
+		}
+		return
+	}
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch strings.Join(parts[2:], "/") {
+	case "points":
+		var req qdrantUpsertPointsReq
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, p := range req.Points {
+			points[p.ID] = p
+		}
+		reply(map[string]any{"status": "completed"})
+	case "points/delete":
+		var req qdrantDeletePointsReq
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, id := range req.Points {
+			delete(points, id)
+		}
+		reply(map[string]any{"status": "completed"})
+	case "points/scroll":
+		var list []map[string]any
+		for _, p := range points {
+			list = append(list, map[string]any{"id": p.ID, "vector": p.Vector, "payload": p.Payload})
+		}
+		reply(map[string]any{"points": list, "next_page_offset": nil})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Contoh yang di-approve saat retrain berjalan harus ada di collection baru setelah alias
+// dipindahkan, dan contoh lama yang digantikannya tidak boleh kembali dari daftar item job.
+func TestSyncRAGCollectionKeepsChangesMadeDuringRetrain(t *testing.T) {
+	fake := newFakeQdrant()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{
+		QdrantURL:            srv.URL,
+		QdrantCollectionName: "rag",
+		EmbeddingVectorSize:  2,
+		QdrantDistanceMetric: "Cosine",
+	}
+
+	ddl := newRagItem("ddl", "CREATE TABLE nasabah (cif text);", "CREATE TABLE nasabah (cif text);", map[string]interface{}{})
+	oldExample := sqlExampleRagItem(SqlExample{PromptOnly: "jumlah nasabah", FullContent: "SELECT COUNT(*) FROM nasabah;"})
+	newExample := sqlExampleRagItem(SqlExample{PromptOnly: "jumlah nasabah", FullContent: "SELECT COUNT(cif) FROM nasabah;"})
+
+	fake.collections["rag_lama"] = map[string]qdrantPoint{
+		oldExample.pointID(): {ID: oldExample.pointID(), Vector: []float32{1, 0}, Payload: oldExample.Payload},
+	}
+	fake.aliases["rag"] = "rag_lama"
+
+	approved := false
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		if !approved {
+			// Approve feedback mendarat di tengah retrain: contoh baru menggantikan contoh lama
+			approved = true
+			change := ragChange{
+				upsert:   &qdrantPoint{ID: newExample.pointID(), Vector: []float32{0, 1}, Payload: newExample.Payload},
+				deleteID: oldExample.pointID(),
+			}
+			if err := applyRAGChange(ctx, change); err != nil {
+				t.Errorf("applyRAGChange: %v", err)
+			}
+		}
+		return []float32{1, 1}, nil
+	}
+
+	result, err := SyncRAGCollection(context.Background(), embed, []ragItem{ddl, oldExample}, TrainOptions{})
+	if err != nil {
+		t.Fatalf("SyncRAGCollection: %v", err)
+	}
+
+	if got := fake.aliases["rag"]; got != result.Collection {
+		t.Fatalf("alias menunjuk ke %q, want %q", got, result.Collection)
+	}
+	want := []string{ddl.pointID(), newExample.pointID()}
+	sort.Strings(want)
+	if got := fake.pointIDs(result.Collection); !reflect.DeepEqual(got, want) {
+		t.Errorf("point di collection baru = %v, want %v (DDL + contoh hasil approve)", got, want)
+	}
+	if _, ok := fake.collections["rag_lama"]; ok {
+		t.Error("collection lama tidak dihapus setelah alias dipindahkan")
+	}
+	ragLiveMu.Lock()
+	pending := len(ragShadowChanges)
+	ragLiveMu.Unlock()
+	if pending != 0 {
+		t.Errorf("catatan perubahan shadow tidak dibersihkan: %d", pending)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// ReferenceTable adalah tabel master yang isinya (ID -> label) disisipkan ke prompt.
+type ReferenceTable struct {
+	TableName   string `json:"table_name"`
+	IDColumn    string `json:"id_column"`
+	LabelColumn string `json:"label_column"`
+	Source      string `json:"source"` // "config" atau "discovered"
+}
+
+// quoteIdent meng-quote identifier PostgreSQL (nama tabel/kolom) secara aman.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ResolveReferenceTables menentukan tabel referensi: dari tabel konfigurasi ai_reference_tables
+// jika berisi data, jika tidak ditemukan otomatis dari pola nama atau tabel kecil yang direferensikan FK.
+func ResolveReferenceTables(ctx context.Context) ([]ReferenceTable, error) {
+	if DbInstance == nil {
+		return nil, fmt.Errorf("koneksi database belum siap")
+	}
+
+	schema, err := getSchemaFromConnStr()
+	if err != nil {
+		return nil, err
+	}
+
+	configured, err := loadConfiguredReferenceTables(ctx, schema)
+	if err != nil {
+		log.Printf("Info: Tabel konfigurasi ai_reference_tables tidak terbaca, memakai auto-discovery: %v", err)
+	}
+	if len(configured) > 0 {
+		return configured, nil
+	}
+
+	return discoverReferenceTables(ctx, schema)
+}
+
+func loadConfiguredReferenceTables(ctx context.Context, schema string) ([]ReferenceTable, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		table_name,
+		COALESCE(id_column, ''),
+		COALESCE(label_column, '')
+	FROM
+		%s
+	WHERE
+		is_active = true
+	ORDER BY
+		table_name;
+	`, metaTable("ai_reference_tables"))
+
+	rows, err := DbInstance.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []ReferenceTable
+	for rows.Next() {
+		var t ReferenceTable
+		if err := rows.Scan(&t.TableName, &t.IDColumn, &t.LabelColumn); err != nil {
+			return nil, err
+		}
+		t.Source = "config"
+		tables = append(tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Kolom yang tidak diisi di konfigurasi di-resolve dari constraint
+	for i := range tables {
+		if tables[i].IDColumn == "" || tables[i].LabelColumn == "" {
+			idCol, labelCol, err := resolveReferenceColumns(ctx, schema, tables[i].TableName)
+			if err != nil {
+				log.Printf("Warning: Gagal resolve kolom untuk tabel referensi %s: %v", tables[i].TableName, err)
+				continue
+			}
+			if tables[i].IDColumn == "" {
+				tables[i].IDColumn = idCol
+			}
+			if tables[i].LabelColumn == "" {
+				tables[i].LabelColumn = labelCol
+			}
+		}
+	}
+	return tables, nil
+}
+
+func discoverReferenceTables(ctx context.Context, schema string) ([]ReferenceTable, error) {
+	pattern := "master_%"
+	maxRows := 200
+	if AppConfig != nil {
+		pattern = AppConfig.ReferenceTablePattern
+		maxRows = AppConfig.ReferenceTableMaxRows
+	}
+
+	// reltuples = -1 berarti tabel belum pernah di-ANALYZE; hanya ikut jika cocok pola nama.
+	rows, err := DbInstance.QueryContext(ctx, `
+	SELECT
+		cl.relname
+	FROM
+		pg_class cl
+		JOIN pg_namespace ns ON cl.relnamespace = ns.oid
+	WHERE
+		ns.nspname = $1
+		AND cl.relkind IN ('r', 'p')
+		AND (
+			($2 <> '' AND cl.relname LIKE $2)
+			OR (
+				cl.oid IN (SELECT confrelid FROM pg_constraint WHERE contype = 'f')
+				AND cl.reltuples >= 0
+				AND cl.reltuples <= $3
+			)
+		)
+	ORDER BY
+		cl.relname;
+	`, schema, pattern, maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencari tabel referensi: %w", err)
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var tables []ReferenceTable
+	for _, name := range names {
+		idCol, labelCol, err := resolveReferenceColumns(ctx, schema, name)
+		if err != nil {
+			log.Printf("Info: Tabel %s dilewati sebagai referensi: %v", name, err)
+			continue
+		}
+		tables = append(tables, ReferenceTable{
+			TableName:   name,
+			IDColumn:    idCol,
+			LabelColumn: labelCol,
+			Source:      "discovered",
+		})
+	}
+	return tables, nil
+}
+
+// resolveReferenceColumns memilih kolom ID dari primary key satu-kolom, dan kolom label dari
+// kolom teks pertama non-PK (prioritas: nama_*, name, label, deskripsi).
+func resolveReferenceColumns(ctx context.Context, schema, tableName string) (string, string, error) {
+	var idCol string
+	err := DbInstance.QueryRowContext(ctx, `
+	SELECT
+		a.attname
+	FROM
+		pg_index i
+		JOIN pg_class cl ON i.indrelid = cl.oid
+		JOIN pg_namespace ns ON cl.relnamespace = ns.oid
+		JOIN pg_attribute a ON a.attrelid = cl.oid AND a.attnum = i.indkey[0]
+	WHERE
+		ns.nspname = $1
+		AND cl.relname = $2
+		AND i.indisprimary
+		AND i.indnatts = 1;
+	`, schema, tableName).Scan(&idCol)
+	if err != nil {
+		return "", "", fmt.Errorf("tidak punya primary key satu kolom: %w", err)
+	}
+
+	rows, err := DbInstance.QueryContext(ctx, `
+	SELECT
+		column_name
+	FROM
+		information_schema.columns
+	WHERE
+		table_schema = $1
+		AND table_name = $2
+		AND data_type IN ('character varying', 'text', 'character')
+	ORDER BY
+		ordinal_position;
+	`, schema, tableName)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	var candidates []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return "", "", err
+		}
+		if col != idCol {
+			candidates = append(candidates, col)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+	if len(candidates) == 0 {
+		return "", "", fmt.Errorf("tidak ada kolom teks untuk label")
+	}
+
+	rank := func(col string) int {
+		switch {
+		case strings.HasPrefix(col, "nama"):
+			return 0
+		case col == "name" || col == "label":
+			return 1
+		case strings.HasPrefix(col, "deskripsi") || col == "description":
+			return 2
+		default:
+			return 3
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return rank(candidates[a]) < rank(candidates[b])
+	})
+
+	return idCol, candidates[0], nil
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Dependency eksternal yang dilindungi retry & circuit breaker.
+const (
+	depGroq   = "groq"
+	depOllama = "ollama"
+	depGemini = "gemini"
+	depQdrant = "qdrant"
+)
+
+// ErrCircuitOpen dikembalikan tanpa memanggil dependency selama circuit breaker-nya terbuka.
+var ErrCircuitOpen = errors.New("circuit breaker terbuka")
+
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+// circuitBreaker membuka sirkuit setelah CIRCUIT_BREAKER_FAILURE_THRESHOLD kegagalan beruntun,
+// menolak panggilan selama CIRCUIT_BREAKER_COOLDOWN_SECONDS, lalu meloloskan satu panggilan
+// percobaan (half-open) yang menentukan sirkuit ditutup atau dibuka lagi.
+type circuitBreaker struct {
+	name string
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	trips       int
+	openedAt    time.Time
+	probing     bool
+	lastError   string
+	lastFailure time.Time
+}
+
+// CircuitBreakerStatus adalah kondisi circuit breaker satu dependency untuk output health.
+type CircuitBreakerStatus struct {
+	Dependency          string     `json:"dependency"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Trips               int        `json:"trips"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*circuitBreaker{}
+)
+
+func breakerFor(dep string) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[dep]
+	if !ok {
+		b = &circuitBreaker{name: dep, state: circuitClosed}
+		breakers[dep] = b
+	}
+	return b
+}
+
+// CircuitBreakerStatuses mengembalikan kondisi semua circuit breaker, diurutkan per dependency.
+func CircuitBreakerStatuses() []CircuitBreakerStatus {
+	for _, dep := range []string{depGroq, depOllama, depGemini, depQdrant} {
+		breakerFor(dep)
+	}
+	breakersMu.Lock()
+	list := make([]*circuitBreaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	statuses := make([]CircuitBreakerStatus, 0, len(list))
+	for _, b := range list {
+		statuses = append(statuses, b.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Dependency < statuses[j].Dependency })
+	return statuses
+}
+
+func breakerSettings() (threshold int, cooldown time.Duration) {
+	threshold, cooldown = 5, 30*time.Second
+	if AppConfig != nil {
+		threshold, cooldown = AppConfig.CircuitBreakerThreshold, AppConfig.CircuitBreakerCooldown
+	}
+	return threshold, cooldown
+}
+
+// allow memutuskan apakah panggilan boleh diteruskan ke dependency.
+func (b *circuitBreaker) allow() error {
+	threshold, cooldown := breakerSettings()
+	if threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == circuitOpen {
+		if time.Since(b.openedAt) < cooldown {
+			return fmt.Errorf("%w untuk %s (error terakhir: %s)", ErrCircuitOpen, b.name, b.lastError)
+		}
+		b.state = circuitHalfOpen
+		b.probing = false
+	}
+	if b.state == circuitHalfOpen {
+		if b.probing {
+			return fmt.Errorf("%w untuk %s (menunggu panggilan percobaan)", ErrCircuitOpen, b.name)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record mencatat hasil satu panggilan. counted=false dipakai saat panggilan tidak menggambarkan
+// kesehatan dependency (mis. context pemanggil dibatalkan).
+func (b *circuitBreaker) record(err error, counted bool) {
+	threshold, _ := breakerSettings()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasProbe := b.probing
+	b.probing = false
+	if !counted {
+		return
+	}
+	if err == nil {
+		if b.state != circuitClosed {
+			log.Printf("✅ Circuit breaker %s tertutup kembali.", b.name)
+		}
+		b.state = circuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	b.lastFailure = time.Now()
+	if threshold > 0 && (wasProbe || b.failures >= threshold) && b.state != circuitOpen {
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+		b.trips++
+		log.Printf("🔌 Circuit breaker %s terbuka setelah %d kegagalan beruntun: %v", b.name, b.failures, err)
+	}
+}
+
+func (b *circuitBreaker) status() CircuitBreakerStatus {
+	_, cooldown := breakerSettings()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := CircuitBreakerStatus{
+		Dependency:          b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+		LastError:           b.lastError,
+	}
+	if b.state == circuitOpen {
+		until := b.openedAt.Add(cooldown)
+		s.OpenUntil = &until
+	}
+	if !b.lastFailure.IsZero() {
+		at := b.lastFailure
+		s.LastFailureAt = &at
+	}
+	return s
+}
+
+// callOutcome adalah hasil satu percobaan panggilan ke dependency.
+type callOutcome struct {
+	Err error
+	// Retryable: percobaan boleh diulang (429, 5xx/unavailable pada operasi idempoten).
+	Retryable bool
+	// Failure: dihitung sebagai kegagalan dependency oleh circuit breaker.
+	Failure bool
+	// RetryAfter dari header Retry-After; menggantikan backoff jika lebih lama.
+	RetryAfter time.Duration
+}
+
+// resilientCall menjalankan attempt lewat circuit breaker dep dengan retry berbasis exponential
+// backoff + jitter (HTTP_RETRY_MAX kali). Retry berhenti jika ctx selesai atau jeda berikutnya
+// melewati deadline ctx.
+func resilientCall(ctx context.Context, dep string, attempt func(ctx context.Context) callOutcome) error {
+	breaker := breakerFor(dep)
+	maxRetries, baseDelay, maxDelay := 2, 200*time.Millisecond, 5*time.Second
+	if AppConfig != nil {
+		maxRetries, baseDelay, maxDelay = AppConfig.HTTPRetryMax, AppConfig.HTTPRetryBaseDelay, AppConfig.HTTPRetryMaxDelay
+	}
+
+	for i := 0; ; i++ {
+		if err := breaker.allow(); err != nil {
+			return err
+		}
+		out := attempt(ctx)
+		// Pembatalan/deadline milik pemanggil bukan tanda dependency bermasalah
+		callerDone := out.Err != nil && ctx.Err() != nil
+		breaker.record(out.Err, !callerDone && (out.Err == nil || out.Failure))
+		if out.Err == nil || !out.Retryable || callerDone || i >= maxRetries {
+			return out.Err
+		}
+
+		delay := backoffDelay(i, baseDelay, maxDelay)
+		if out.RetryAfter > delay {
+			delay = out.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return out.Err
+		}
+		log.Printf("⚠️ Panggilan %s gagal (percobaan %d/%d): %v. Retry dalam %s.", dep, i+1, maxRetries+1, out.Err, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return out.Err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoffDelay menghitung jeda retry ke-n: base × 2^n (maksimal maxDelay) dengan jitter 50–100%.
+func backoffDelay(n int, base, maxDelay time.Duration) time.Duration {
+	d := base << n
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter membaca header Retry-After dalam detik atau format HTTP-date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// resilientHTTPClient adalah client HTTP bersama untuk satu dependency: timeout per percobaan,
+// retry, dan circuit breaker.
+type resilientHTTPClient struct {
+	dep    string
+	client *http.Client
+}
+
+// doOption mengubah perilaku satu panggilan DoJSON.
+type doOption func(*doOptions)
+
+type doOptions struct {
+	idempotent *bool
+	probe      bool
+}
+
+// withIdempotent menandai apakah panggilan aman diulang setelah error jaringan atau 5xx.
+// Tanpa opsi ini, POST/PATCH dianggap tidak idempoten dan method lain idempoten.
+func withIdempotent(v bool) doOption {
+	return func(o *doOptions) { o.idempotent = &v }
+}
+
+// withProbe menjalankan tepat satu percobaan tanpa retry dan tanpa circuit breaker (health
+// check): latensi yang dilaporkan murni, hasil probe tidak ikut membuka sirkuit trafik nyata,
+// dan dependency tetap diperiksa walau sirkuitnya sedang terbuka.
+func withProbe() doOption {
+	return func(o *doOptions) { o.probe = true }
+}
+
+var (
+	httpClientsMu sync.Mutex
+	httpClients   = map[string]*resilientHTTPClient{}
+)
+
+// httpClient mengembalikan client bersama untuk dependency dep (dibuat saat pertama dipakai).
+func httpClient(dep string) *resilientHTTPClient {
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	if c, ok := httpClients[dep]; ok {
+		return c
+	}
+
+	timeout := 60 * time.Second
+	if AppConfig != nil {
+		switch dep {
+		case depGroq:
+			timeout = AppConfig.GroqTimeout
+		case depOllama:
+			timeout = AppConfig.OllamaTimeout
+		case depQdrant:
+			timeout = AppConfig.QdrantTimeout
+		}
+	}
+	c := &resilientHTTPClient{dep: dep, client: &http.Client{Timeout: timeout}}
+	httpClients[dep] = c
+	return c
+}
+
+// httpStatusError menandai percobaan yang gagal karena status HTTP (bukan error jaringan).
+type httpStatusError struct {
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("status HTTP %d", e.StatusCode)
+}
+
+// DoJSON mengirim body sebagai JSON dan membaca seluruh response. Status non-2xx tidak menjadi
+// error: response terakhir dikembalikan agar pemanggil bisa memeriksa StatusCode dan body.
+// 429/503 selalu diulang; error jaringan dan 5xx lain hanya diulang untuk panggilan idempoten.
+func (c *resilientHTTPClient) DoJSON(ctx context.Context, method, url string, body any, header http.Header, opts ...doOption) (*http.Response, []byte, error) {
+	var payload []byte
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gagal marshal JSON: %w", err)
+		}
+		payload = b
+	}
+	var o doOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+	if o.idempotent != nil {
+		idempotent = *o.idempotent
+	}
+
+	var resp *http.Response
+	var respBody []byte
+	attempt := func(ctx context.Context) callOutcome {
+		resp, respBody = nil, nil
+
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return callOutcome{Err: fmt.Errorf("gagal buat request: %w", err)}
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		r, err := c.client.Do(req)
+		if err != nil {
+			return callOutcome{Err: fmt.Errorf("gagal call %s %s: %w", method, url, err), Retryable: idempotent, Failure: true}
+		}
+		b, readErr := io.ReadAll(r.Body)
+		r.Body.Close()
+		if readErr != nil {
+			return callOutcome{Err: fmt.Errorf("gagal baca response body: %w", readErr), Retryable: idempotent, Failure: true}
+		}
+		resp, respBody = r, b
+
+		switch {
+		case r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusServiceUnavailable:
+			// Request belum diproses server, aman diulang untuk semua method
+			return callOutcome{Err: &httpStatusError{r.StatusCode}, Retryable: true, Failure: true,
+				RetryAfter: parseRetryAfter(r.Header.Get("Retry-After"))}
+		case r.StatusCode >= 500:
+			return callOutcome{Err: &httpStatusError{r.StatusCode}, Retryable: idempotent, Failure: true}
+		}
+		return callOutcome{}
+	}
+
+	var err error
+	if o.probe {
+		err = attempt(ctx).Err
+	} else {
+		err = resilientCall(ctx, c.dep, attempt)
+	}
+
+	var statusErr *httpStatusError
+	if err != nil && errors.As(err, &statusErr) && resp != nil {
+		return resp, respBody, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, respBody, nil
+}
+
+// grpcOutcome mengklasifikasikan error gRPC (Qdrant, Gemini) untuk resilientCall. Semua
+// panggilan gRPC yang dibungkus bersifat baca/embedding sehingga aman diulang.
+func grpcOutcome(err error) callOutcome {
+	if err == nil {
+		return callOutcome{}
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted, codes.Internal:
+		return callOutcome{Err: err, Retryable: true, Failure: true}
+	case codes.Canceled:
+		return callOutcome{Err: err}
+	}
+	return callOutcome{Err: err}
+}
+
+// googleAPIOutcome mengklasifikasikan error client REST Google (Gemini embedding). Error tanpa
+// respons HTTP dianggap gangguan jaringan dan boleh diulang karena embedding bersifat idempoten.
+func googleAPIOutcome(err error) callOutcome {
+	if err == nil {
+		return callOutcome{}
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return callOutcome{Err: err, Retryable: true, Failure: true}
+	}
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests || apiErr.Code == http.StatusServiceUnavailable:
+		return callOutcome{Err: err, Retryable: true, Failure: true, RetryAfter: parseRetryAfter(apiErr.Header.Get("Retry-After"))}
+	case apiErr.Code >= 500:
+		return callOutcome{Err: err, Retryable: true, Failure: true}
+	}
+	return callOutcome{Err: err}
+}
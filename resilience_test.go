@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{CircuitBreakerThreshold: 2, CircuitBreakerCooldown: time.Minute}
+
+	errDown := errors.New("dependency mati")
+	b := &circuitBreaker{name: "tes", state: circuitClosed}
+	expectState := func(want string) {
+		t.Helper()
+		if b.state != want {
+			t.Fatalf("state = %s, want %s", b.state, want)
+		}
+	}
+
+	b.record(errDown, true)
+	expectState(circuitClosed)
+	b.record(nil, true)
+	if b.failures != 0 {
+		t.Fatalf("sukses harus mereset hitungan kegagalan, failures = %d", b.failures)
+	}
+
+	b.record(errDown, true)
+	b.record(errDown, false) // tidak dihitung (mis. context pemanggil dibatalkan)
+	expectState(circuitClosed)
+	b.record(errDown, true)
+	expectState(circuitOpen)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow selama cooldown = %v, want ErrCircuitOpen", err)
+	}
+
+	// Cooldown lewat: satu panggilan percobaan diloloskan, sisanya ditolak
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("panggilan percobaan ditolak: %v", err)
+	}
+	expectState(circuitHalfOpen)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("panggilan kedua saat half-open = %v, want ErrCircuitOpen", err)
+	}
+
+	// Percobaan gagal membuka sirkuit lagi tanpa menunggu threshold
+	b.record(errDown, true)
+	expectState(circuitOpen)
+	if b.trips != 2 {
+		t.Errorf("trips = %d, want 2", b.trips)
+	}
+
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("panggilan percobaan ditolak: %v", err)
+	}
+	b.record(nil, true)
+	expectState(circuitClosed)
+	if err := b.allow(); err != nil {
+		t.Errorf("allow setelah sirkuit tertutup = %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{CircuitBreakerThreshold: 0, CircuitBreakerCooldown: time.Minute}
+
+	b := &circuitBreaker{name: "tes", state: circuitClosed}
+	for i := 0; i < 10; i++ {
+		b.record(errors.New("gagal"), true)
+		if err := b.allow(); err != nil {
+			t.Fatalf("breaker nonaktif menolak panggilan: %v", err)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		base     time.Duration
+		maxDelay time.Duration
+		wantMax  time.Duration
+	}{
+		{"percobaan pertama", 0, 200 * time.Millisecond, 5 * time.Second, 200 * time.Millisecond},
+		{"eksponensial", 3, 200 * time.Millisecond, 5 * time.Second, 1600 * time.Millisecond},
+		{"dibatasi maxDelay", 10, 200 * time.Millisecond, 5 * time.Second, 5 * time.Second},
+		{"overflow shift dibatasi maxDelay", 70, time.Second, 5 * time.Second, 5 * time.Second},
+		{"tanpa jeda", 2, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := backoffDelay(tt.n, tt.base, tt.maxDelay)
+				if got < tt.wantMax/2 || got > tt.wantMax {
+					t.Fatalf("backoffDelay = %s, want dalam [%s, %s]", got, tt.wantMax/2, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{"kosong", "", 0, 0},
+		{"detik", "3", 3 * time.Second, 3 * time.Second},
+		{"nol", "0", 0, 0},
+		{"negatif", "-5", 0, 0},
+		{"tidak valid", "nanti", 0, 0},
+		{"HTTP-date di masa depan", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{"HTTP-date di masa lalu", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.in); got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("parseRetryAfter(%q) = %s, want dalam [%s, %s]", tt.in, got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestDoJSONRetryByIdempotency(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{HTTPRetryMax: 2, HTTPRetryBaseDelay: time.Millisecond, HTTPRetryMaxDelay: time.Millisecond}
+
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		opts      []doOption
+		wantCalls int32
+	}{
+		{"POST tanpa opsi tidak diulang saat 500", http.MethodPost, http.StatusInternalServerError, nil, 1},
+		{"POST idempoten diulang saat 500", http.MethodPost, http.StatusInternalServerError, []doOption{withIdempotent(true)}, 3},
+		{"GET diulang saat 500", http.MethodGet, http.StatusInternalServerError, nil, 3},
+		{"DELETE non-idempoten tidak diulang", http.MethodDelete, http.StatusInternalServerError, []doOption{withIdempotent(false)}, 1},
+		{"503 selalu diulang", http.MethodPost, http.StatusServiceUnavailable, []doOption{withIdempotent(false)}, 3},
+		{"4xx tidak diulang", http.MethodGet, http.StatusBadRequest, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := &resilientHTTPClient{dep: "tes-" + tt.name, client: srv.Client()}
+			resp, _, err := c.DoJSON(context.Background(), tt.method, srv.URL, map[string]string{"a": "b"}, nil, tt.opts...)
+			if err != nil {
+				t.Fatalf("DoJSON error: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("jumlah panggilan = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoJSONProbeBypassesRetryAndBreaker(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+	AppConfig = &Config{
+		HTTPRetryMax: 2, HTTPRetryBaseDelay: time.Millisecond, HTTPRetryMaxDelay: time.Millisecond,
+		CircuitBreakerThreshold: 1, CircuitBreakerCooldown: time.Minute,
+	}
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	dep := "tes-probe"
+	breaker := breakerFor(dep)
+	c := &resilientHTTPClient{dep: dep, client: srv.Client()}
+
+	resp, _, err := c.DoJSON(context.Background(), http.MethodGet, srv.URL, nil, nil, withProbe())
+	if err != nil || resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("probe = (%v, %v), want status 500 tanpa error", resp, err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("probe memanggil dependency %d kali, want 1", got)
+	}
+	if s := breaker.status(); s.State != circuitClosed || s.ConsecutiveFailures != 0 {
+		t.Errorf("probe tercatat di circuit breaker: %+v", s)
+	}
+
+	// Trafik biasa membuka sirkuit; probe tetap menghubungi dependency
+	if _, _, err := c.DoJSON(context.Background(), http.MethodGet, srv.URL, nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("DoJSON setelah kegagalan = %v, want ErrCircuitOpen", err)
+	}
+	if s := breaker.status(); s.State != circuitOpen {
+		t.Fatalf("state = %s, want open", s.State)
+	}
+	calls.Store(0)
+	if _, _, err := c.DoJSON(context.Background(), http.MethodGet, srv.URL, nil, nil, withProbe()); err != nil {
+		t.Errorf("probe saat sirkuit terbuka = %v, want tetap memanggil dependency", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("probe saat sirkuit terbuka memanggil dependency %d kali, want 1", got)
+	}
+}
@@ -4,11 +4,27 @@ import "net/http"
 
 func RegisterRoutes() {
 	http.HandleFunc("/health", HandleHealthCheck)
+	http.HandleFunc("/health/live", HandleHealthLive)
+	http.HandleFunc("/health/ready", HandleHealthReady)
 	http.HandleFunc("/api/query", HandleDynamicQuery)
 	http.HandleFunc("/api/feedback/koreksi", HandleFeedbackKoreksi)
+	http.HandleFunc("/admin/absurd-keywords", HandleAdminAbsurdKeywords)
+	http.HandleFunc("/admin/absurd-keywords/test", HandleAdminAbsurdKeywordsTest)
+	http.HandleFunc("/admin/absurd-keywords/{id}", HandleAdminAbsurdKeyword)
+	http.HandleFunc("/admin/dictionary", HandleAdminDictionary)
+	http.HandleFunc("/admin/dictionary/{id}", HandleAdminDictionaryItem)
+	http.HandleFunc("/admin/dictionary/{id}/history", HandleAdminDictionaryHistory)
+	http.HandleFunc("/admin/feedback", HandleAdminFeedbackList)
+	http.HandleFunc("/admin/feedback/{id}", HandleAdminFeedbackGet)
+	http.HandleFunc("/admin/feedback/{id}/{action}", HandleAdminFeedbackReview)
+	http.HandleFunc("/admin/prompt-templates", HandleAdminPromptTemplates)
+	http.HandleFunc("/admin/prompt-templates/reload", HandleAdminPromptTemplatesReload)
 	http.HandleFunc("/admin/retrain", HandleAdminRetrain)
+	http.HandleFunc("/admin/retrain/{id}", HandleAdminRetrainJob)
 	http.HandleFunc("/admin/qdrant/list", HandleAdminListQdrant)
 	http.HandleFunc("/admin/qdrant/delete", HandleAdminDeleteQdrant)
 	http.HandleFunc("/admin/cache/create", HandleAdminCacheCreate)
 	http.HandleFunc("/admin/qdrant/update", HandleAdminQdrantUpdate)
+	http.HandleFunc("/admin/schema/snapshot", HandleAdminSchemaSnapshot)
+	http.HandleFunc("/admin/schema/refresh", HandleAdminSchemaRefresh)
 }
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ColumnInfo adalah metadata satu kolom hasil introspeksi skema.
+type ColumnInfo struct {
+	Name         string
+	DataType     string
+	IsNullable   bool
+	Comment      string
+	EnumValues   []string
+	SampleValues []string
+}
+
+// TableInfo adalah metadata satu tabel: kolom, constraint (PK/FK/UNIQUE), dan komentar.
+type TableInfo struct {
+	Name        string
+	Comment     string
+	Columns     []ColumnInfo
+	Constraints []string
+	ForeignKeys []string
+}
+
+// IntrospectSchema membaca struktur lengkap skema aktif dari information_schema & pg_catalog.
+// Kolom wajib berhasil dibaca; komentar, constraint, enum dan contoh nilai bersifat opsional.
+func IntrospectSchema(ctx context.Context) ([]TableInfo, error) {
+	if DbInstance == nil {
+		return nil, fmt.Errorf("koneksi database (DbInstance) belum siap")
+	}
+
+	schema, err := getSchemaFromConnStr()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT
+		c.table_name,
+		c.column_name,
+		c.data_type,
+		c.udt_name,
+		c.is_nullable = 'YES',
+		COALESCE(col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position), '')
+	FROM
+		information_schema.columns c
+	WHERE
+		c.table_schema = $1
+	ORDER BY
+		c.table_name,
+		c.ordinal_position;
+	`
+
+	rows, err := DbInstance.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("gagal query information_schema: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []TableInfo
+	tableIndex := make(map[string]int)
+	udtNames := make(map[string]map[string]string)
+
+	for rows.Next() {
+		var tableName, udtName string
+		var col ColumnInfo
+		if err := rows.Scan(&tableName, &col.Name, &col.DataType, &udtName, &col.IsNullable, &col.Comment); err != nil {
+			return nil, err
+		}
+
+		idx, ok := tableIndex[tableName]
+		if !ok {
+			idx = len(tables)
+			tableIndex[tableName] = idx
+			tables = append(tables, TableInfo{Name: tableName})
+			udtNames[tableName] = make(map[string]string)
+		}
+		if col.DataType == "USER-DEFINED" {
+			col.DataType = udtName
+			udtNames[tableName][col.Name] = udtName
+		}
+		tables[idx].Columns = append(tables[idx].Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("tidak ada tabel ditemukan di skema '%s'", schema)
+	}
+
+	if err := loadTableComments(ctx, schema, tables, tableIndex); err != nil {
+		log.Printf("PERINGATAN: Gagal membaca komentar tabel: %v", err)
+	}
+	if err := loadTableConstraints(ctx, schema, tables, tableIndex); err != nil {
+		log.Printf("PERINGATAN: Gagal membaca constraint tabel: %v", err)
+	}
+	if err := loadEnumValues(ctx, schema, tables, udtNames); err != nil {
+		log.Printf("PERINGATAN: Gagal membaca nilai enum: %v", err)
+	}
+	if err := loadSampleValues(ctx, schema, tables, tableIndex); err != nil {
+		log.Printf("PERINGATAN: Gagal membaca contoh nilai kolom: %v", err)
+	}
+
+	return tables, nil
+}
+
+func loadTableComments(ctx context.Context, schema string, tables []TableInfo, tableIndex map[string]int) error {
+	rows, err := DbInstance.QueryContext(ctx, `
+	SELECT
+		cl.relname,
+		COALESCE(obj_description(cl.oid, 'pg_class'), '')
+	FROM
+		pg_class cl
+		JOIN pg_namespace ns ON cl.relnamespace = ns.oid
+	WHERE
+		ns.nspname = $1
+		AND cl.relkind IN ('r', 'p', 'v', 'm');
+	`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, comment string
+		if err := rows.Scan(&tableName, &comment); err != nil {
+			return err
+		}
+		if idx, ok := tableIndex[tableName]; ok {
+			tables[idx].Comment = comment
+		}
+	}
+	return rows.Err()
+}
+
+func loadTableConstraints(ctx context.Context, schema string, tables []TableInfo, tableIndex map[string]int) error {
+	rows, err := DbInstance.QueryContext(ctx, `
+	SELECT
+		cl.relname,
+		c.contype,
+		pg_get_constraintdef(c.oid)
+	FROM
+		pg_constraint c
+		JOIN pg_class cl ON c.conrelid = cl.oid
+		JOIN pg_namespace ns ON cl.relnamespace = ns.oid
+	WHERE
+		ns.nspname = $1
+		AND c.contype IN ('p', 'u', 'f')
+	ORDER BY
+		cl.relname,
+		CASE c.contype WHEN 'p' THEN 0 WHEN 'u' THEN 1 ELSE 2 END,
+		c.conname;
+	`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, conType, definition string
+		if err := rows.Scan(&tableName, &conType, &definition); err != nil {
+			return err
+		}
+		idx, ok := tableIndex[tableName]
+		if !ok {
+			continue
+		}
+		tables[idx].Constraints = append(tables[idx].Constraints, definition)
+		if conType == "f" {
+			tables[idx].ForeignKeys = append(tables[idx].ForeignKeys, definition)
+		}
+	}
+	return rows.Err()
+}
+
+func loadEnumValues(ctx context.Context, schema string, tables []TableInfo, udtNames map[string]map[string]string) error {
+	rows, err := DbInstance.QueryContext(ctx, `
+	SELECT
+		t.typname,
+		e.enumlabel
+	FROM
+		pg_type t
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		JOIN pg_namespace ns ON t.typnamespace = ns.oid
+	WHERE
+		ns.nspname = $1
+	ORDER BY
+		t.typname,
+		e.enumsortorder;
+	`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	enums := make(map[string][]string)
+	for rows.Next() {
+		var typeName, label string
+		if err := rows.Scan(&typeName, &label); err != nil {
+			return err
+		}
+		enums[typeName] = append(enums[typeName], label)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range tables {
+		for j := range tables[i].Columns {
+			col := &tables[i].Columns[j]
+			if typeName, ok := udtNames[tables[i].Name][col.Name]; ok {
+				col.EnumValues = enums[typeName]
+			}
+		}
+	}
+	return nil
+}
+
+// loadSampleValues mengambil beberapa nilai yang sering muncul untuk kolom ber-kardinalitas rendah
+// dari pg_stats (butuh ANALYZE), sehingga tidak perlu scan tabel saat runtime.
+func loadSampleValues(ctx context.Context, schema string, tables []TableInfo, tableIndex map[string]int) error {
+	if AppConfig == nil || AppConfig.SchemaSampleValues <= 0 {
+		return nil
+	}
+
+	rows, err := DbInstance.QueryContext(ctx, `
+	SELECT
+		tablename,
+		attname,
+		most_common_vals::text
+	FROM
+		pg_stats
+	WHERE
+		schemaname = $1
+		AND most_common_vals IS NOT NULL
+		AND n_distinct > 0
+		AND n_distinct <= $2;
+	`, schema, AppConfig.SchemaSampleMaxDistinct)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableName, columnName, values string
+		if err := rows.Scan(&tableName, &columnName, &values); err != nil {
+			return err
+		}
+		idx, ok := tableIndex[tableName]
+		if !ok {
+			continue
+		}
+		samples := parsePgArrayText(values)
+		if len(samples) > AppConfig.SchemaSampleValues {
+			samples = samples[:AppConfig.SchemaSampleValues]
+		}
+		for j := range tables[idx].Columns {
+			if tables[idx].Columns[j].Name == columnName {
+				tables[idx].Columns[j].SampleValues = samples
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// parsePgArrayText mengurai literal array PostgreSQL seperti {a,b,"c d"} menjadi slice string.
+// Elemen NULL tanpa kutip dilewati; "NULL" berkutip tetap dianggap teks.
+func parsePgArrayText(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "{")
+	s = strings.TrimSuffix(s, "}")
+	if s == "" {
+		return nil
+	}
+
+	var values []string
+	var current strings.Builder
+	inQuotes, escaped, quoted := false, false, false
+	flush := func() {
+		if v := current.String(); quoted || !strings.EqualFold(v, "NULL") {
+			values = append(values, v)
+		}
+		current.Reset()
+		quoted = false
+	}
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case r == ',' && !inQuotes:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return values
+}
+
+// renderTableDDL menulis TableInfo sebagai DDL beranotasi (-- komentar, enum, contoh nilai).
+func renderTableDDL(t TableInfo) string {
+	var sb strings.Builder
+	if t.Comment != "" {
+		sb.WriteString(fmt.Sprintf("-- %s\n", oneLine(t.Comment)))
+	}
+	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", t.Name))
+
+	lines := make([]string, 0, len(t.Columns)+len(t.Constraints))
+	notes := make([]string, 0, len(t.Columns)+len(t.Constraints))
+	for _, col := range t.Columns {
+		line := fmt.Sprintf("    %s %s", col.Name, col.DataType)
+		if !col.IsNullable {
+			line += " NOT NULL"
+		}
+
+		var parts []string
+		if col.Comment != "" {
+			parts = append(parts, oneLine(col.Comment))
+		}
+		if len(col.EnumValues) > 0 {
+			parts = append(parts, "Nilai: "+quoteValues(col.EnumValues))
+		} else if len(col.SampleValues) > 0 {
+			parts = append(parts, "Contoh nilai: "+quoteValues(col.SampleValues))
+		}
+
+		lines = append(lines, line)
+		notes = append(notes, strings.Join(parts, ". "))
+	}
+	for _, c := range t.Constraints {
+		lines = append(lines, "    "+c)
+		notes = append(notes, "")
+	}
+
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			line += ","
+		}
+		if notes[i] != "" {
+			line += " -- " + notes[i]
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(");")
+	return sb.String()
+}
+
+// quoteValues menulis nilai sebagai literal SQL; petik tunggal di dalam nilai digandakan agar
+// DDL yang dilihat LLM tetap valid.
+func quoteValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + strings.ReplaceAll(oneLine(v), "'", "''") + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePgArrayText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"kosong", "{}", nil},
+		{"elemen sederhana", "{AKTIF,TUTUP,BLOKIR}", []string{"AKTIF", "TUTUP", "BLOKIR"}},
+		{"elemen berkutip dengan spasi dan koma", `{"Jakarta Pusat","Bandung, Jawa Barat"}`, []string{"Jakarta Pusat", "Bandung, Jawa Barat"}},
+		{"kutip ganda di-escape", `{"kata \"kunci\"",biasa}`, []string{`kata "kunci"`, "biasa"}},
+		{"backslash di-escape", `{"C:\\data"}`, []string{`C:\data`}},
+		{"petik tunggal apa adanya", "{O'Brien}", []string{"O'Brien"}},
+		{"NULL tanpa kutip dilewati", "{a,NULL,b,null}", []string{"a", "b"}},
+		{"NULL berkutip tetap teks", `{"NULL"}`, []string{"NULL"}},
+		{"string kosong berkutip", `{"",x}`, []string{"", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePgArrayText(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePgArrayText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteValues(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want string
+	}{
+		{"biasa", []string{"A", "B"}, "'A', 'B'"},
+		{"petik tunggal digandakan", []string{"O'Brien"}, "'O''Brien'"},
+		{"baris baru tidak memutus komentar", []string{"baris\nkedua"}, "'baris kedua'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteValues(tt.in); got != tt.want {
+				t.Errorf("quoteValues(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTableDDL(t *testing.T) {
+	tests := []struct {
+		name  string
+		table TableInfo
+		want  string
+	}{
+		{
+			name: "komentar, enum, contoh nilai dan constraint",
+			table: TableInfo{
+				Name:    "public.nasabah",
+				Comment: "Data master\n  nasabah",
+				Columns: []ColumnInfo{
+					{Name: "cif", DataType: "varchar(20)", Comment: "Nomor CIF"},
+					{Name: "status", DataType: "status_nasabah", IsNullable: true, EnumValues: []string{"AKTIF", "TUTUP"}},
+					{Name: "nama", DataType: "text", SampleValues: []string{"O'Brien", "Siti"}},
+				},
+				Constraints: []string{"PRIMARY KEY (cif)"},
+			},
+			want: "-- Data master nasabah\n" +
+				"CREATE TABLE public.nasabah (\n" +
+				"    cif varchar(20) NOT NULL, -- Nomor CIF\n" +
+				"    status status_nasabah, -- Nilai: 'AKTIF', 'TUTUP'\n" +
+				"    nama text NOT NULL, -- Contoh nilai: 'O''Brien', 'Siti'\n" +
+				"    PRIMARY KEY (cif)\n" +
+				");",
+		},
+		{
+			name: "enum didahulukan dari contoh nilai",
+			table: TableInfo{
+				Name: "produk",
+				Columns: []ColumnInfo{
+					{Name: "jenis", DataType: "text", IsNullable: true, Comment: "Jenis produk", EnumValues: []string{"GIRO"}, SampleValues: []string{"X"}},
+				},
+			},
+			want: "CREATE TABLE produk (\n" +
+				"    jenis text -- Jenis produk. Nilai: 'GIRO'\n" +
+				");",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderTableDDL(tt.table); got != tt.want {
+				t.Errorf("renderTableDDL =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
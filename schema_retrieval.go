@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+var ddlTableNameRe = regexp.MustCompile(`(?i)CREATE TABLE\s+([A-Za-z0-9_."]+)\s*\(`)
+
+// ddlTableName mengambil nama tabel dari potongan DDL hasil GetDynamicSchemaContext.
+func ddlTableName(ddl string) string {
+	match := ddlTableNameRe.FindStringSubmatch(ddl)
+	if len(match) < 2 {
+		return ""
+	}
+	return match[1]
+}
+
+// GetRelevantSchemaContext hanya mengembalikan DDL tabel yang relevan dengan prompt:
+// top-k DDL dari Qdrant (category "ddl"), ditambah tabel yang terhubung lewat foreign key.
+// Jika retrieval gagal atau kosong, semua DDL dikembalikan seperti sebelumnya.
+func GetRelevantSchemaContext(ctx context.Context, snap *SchemaSnapshot, promptVector []float32) []string {
+	allDDLs := snap.DDLs
+	if AppConfig == nil || AppConfig.RAGDDLTopK == 0 || len(allDDLs) <= int(AppConfig.RAGDDLTopK) {
+		return allDDLs
+	}
+
+	ddlByTable := make(map[string]string, len(allDDLs))
+	for _, ddl := range allDDLs {
+		if name := ddlTableName(ddl); name != "" {
+			ddlByTable[name] = ddl
+		}
+	}
+
+	limit := AppConfig.RAGDDLTopK
+	searchResponse, err := qdrantQuery(ctx, &pb.QueryPoints{
+		CollectionName: ragReadCollection(),
+		Query:          pb.NewQuery(promptVector...),
+		WithPayload:    pb.NewWithPayload(true),
+		Limit:          &limit,
+		Filter:         categoryFilter("ddl"),
+	})
+	if err != nil {
+		log.Printf("PERINGATAN: Gagal mencari DDL relevan, memakai semua DDL: %v", err)
+		return allDDLs
+	}
+
+	var seedTables []string
+	for _, point := range searchResponse {
+		p := point.GetPayload()
+		if p == nil {
+			continue
+		}
+		tableName := ""
+		if v, ok := p["table_name"]; ok {
+			tableName = v.GetStringValue()
+		}
+		if tableName == "" {
+			if v, ok := p["content"]; ok {
+				tableName = ddlTableName(v.GetStringValue())
+			}
+		}
+		if _, exists := ddlByTable[tableName]; exists {
+			seedTables = append(seedTables, tableName)
+		}
+	}
+	if len(seedTables) == 0 {
+		log.Println("⚠️ Tidak ada DDL relevan dari RAG, memakai semua DDL.")
+		return allDDLs
+	}
+
+	selected := expandTablesByForeignKey(seedTables, snap.ForeignKeys, AppConfig.RAGDDLFKDepth, AppConfig.RAGDDLMaxTables)
+
+	var ddls []string
+	for _, tableName := range selected {
+		if ddl, ok := ddlByTable[tableName]; ok {
+			ddls = append(ddls, ddl)
+		}
+	}
+
+	log.Printf("✅ Schema-aware RAG: %d dari %d tabel dikirim ke prompt (seed: %v).", len(ddls), len(allDDLs), seedTables)
+	return ddls
+}
+
+// expandTablesByForeignKey menambahkan tabel yang direferensikan (hingga depth hop) dan
+// tabel penghubung yang mereferensikan dua atau lebih tabel terpilih.
+func expandTablesByForeignKey(seeds []string, fkGraph map[string][]string, depth int, maxTables int) []string {
+	selected := make(map[string]bool)
+	var order []string
+	add := func(t string) bool {
+		if selected[t] {
+			return false
+		}
+		if maxTables > 0 && len(order) >= maxTables {
+			return false
+		}
+		selected[t] = true
+		order = append(order, t)
+		return true
+	}
+
+	for _, t := range seeds {
+		add(t)
+	}
+
+	frontier := append([]string(nil), order...)
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, t := range frontier {
+			for _, ref := range fkGraph[t] {
+				if add(ref) {
+					next = append(next, ref)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// Tabel penghubung (misal jurnal_transaksi antara rekening & transaksi)
+	var bridges []string
+	for table, refs := range fkGraph {
+		if selected[table] {
+			continue
+		}
+		hits := 0
+		for _, ref := range refs {
+			if selected[ref] {
+				hits++
+			}
+		}
+		if hits >= 2 {
+			bridges = append(bridges, table)
+		}
+	}
+	sort.Strings(bridges)
+	for _, t := range bridges {
+		add(t)
+	}
+
+	return order
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDDLTableName(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want string
+	}{
+		{"dengan schema", "CREATE TABLE public.nasabah (\n  cif varchar(20)\n);", "public.nasabah"},
+		{"huruf kecil dan spasi", "create table   rekening(no_rekening text);", "rekening"},
+		{"identifier berkutip", `CREATE TABLE "Core"."Transaksi" (id int);`, `"Core"."Transaksi"`},
+		{"diawali komentar", "-- Tabel master cabang\nCREATE TABLE cabang (kode text);", "cabang"},
+		{"bukan DDL", "SELECT * FROM nasabah", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ddlTableName(tt.ddl); got != tt.want {
+				t.Errorf("ddlTableName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTablesByForeignKey(t *testing.T) {
+	fk := map[string][]string{
+		"transaksi":        {"rekening"},
+		"rekening":         {"nasabah", "produk"},
+		"nasabah":          {"cabang"},
+		"jurnal_transaksi": {"rekening", "transaksi"},
+		"mutasi_rekening":  {"rekening", "cabang"},
+		"log_audit":        {"nasabah"},
+		"a":                {"b"},
+		"b":                {"a"},
+	}
+
+	tests := []struct {
+		name      string
+		seeds     []string
+		depth     int
+		maxTables int
+		want      []string
+	}{
+		{"tanpa ekspansi", []string{"produk"}, 0, 0, []string{"produk"}},
+		{"satu hop dan penghubung", []string{"transaksi"}, 1, 0, []string{"transaksi", "rekening", "jurnal_transaksi"}},
+		{"dua hop", []string{"transaksi"}, 2, 0, []string{"transaksi", "rekening", "nasabah", "produk", "jurnal_transaksi"}},
+		{"tiga hop menambah penghubung kedua", []string{"transaksi"}, 3, 0,
+			[]string{"transaksi", "rekening", "nasabah", "produk", "cabang", "jurnal_transaksi", "mutasi_rekening"}},
+		{"tabel penghubung antar seed", []string{"rekening", "transaksi"}, 0, 0, []string{"rekening", "transaksi", "jurnal_transaksi"}},
+		{"satu referensi bukan penghubung", []string{"nasabah"}, 0, 0, []string{"nasabah"}},
+		{"maxTables membatasi ekspansi", []string{"transaksi"}, 3, 3, []string{"transaksi", "rekening", "nasabah"}},
+		{"maxTables membatasi seed", []string{"produk", "cabang", "nasabah"}, 0, 2, []string{"produk", "cabang"}},
+		{"seed duplikat", []string{"produk", "produk"}, 1, 0, []string{"produk"}},
+		{"siklus tidak berulang", []string{"a"}, 5, 0, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expandTablesByForeignKey(tt.seeds, fk, tt.depth, tt.maxTables)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandTablesByForeignKey(%v, depth=%d, max=%d) = %v, want %v", tt.seeds, tt.depth, tt.maxTables, got, tt.want)
+			}
+		})
+	}
+}
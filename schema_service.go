@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/url"
@@ -32,77 +33,63 @@ func getSchemaFromConnStr() (string, error) {
 func GetDynamicSchemaContext() ([]string, error) {
 	log.Println("Mulai mengambil skema DDL dinamis dari database...")
 
-	schema, err := getSchemaFromConnStr()
+	tables, err := IntrospectSchema(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	query := `
-	SELECT 
-		table_name, 
-		column_name, 
-		data_type 
-	FROM 
-		information_schema.columns 
-	WHERE 
-		table_schema = $1
-	ORDER BY 
-		table_name, 
-		ordinal_position;
-	`
+	contexts := make([]string, 0, len(tables))
+	for _, t := range tables {
+		contexts = append(contexts, renderTableDDL(t))
+	}
+
+	log.Printf("✅ Berhasil! Mengambil %d potongan DDL dinamis.", len(contexts))
+	return contexts, nil
+}
 
+// GetForeignKeyGraph mengembalikan relasi FK antar tabel: tabel -> daftar tabel yang direferensikan.
+func GetForeignKeyGraph(ctx context.Context) (map[string][]string, error) {
 	if DbInstance == nil {
 		return nil, fmt.Errorf("koneksi database (DbInstance) belum siap")
 	}
 
-	rows, err := DbInstance.QueryContext(context.Background(), query, schema)
+	schema, err := getSchemaFromConnStr()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT DISTINCT
+		src.relname AS table_name,
+		ref.relname AS referenced_table
+	FROM
+		pg_constraint c
+		JOIN pg_class src ON c.conrelid = src.oid
+		JOIN pg_namespace ns ON src.relnamespace = ns.oid
+		JOIN pg_class ref ON c.confrelid = ref.oid
+	WHERE
+		c.contype = 'f'
+		AND ns.nspname = $1;
+	`
+
+	rows, err := DbInstance.QueryContext(ctx, query, schema)
 	if err != nil {
-		return nil, fmt.Errorf("gagal query information_schema: %w", err)
+		return nil, fmt.Errorf("gagal query relasi foreign key: %w", err)
 	}
 	defer rows.Close()
 
-	var contexts []string
-	var currentTable string
-	var sb strings.Builder
-
+	graph := make(map[string][]string)
 	for rows.Next() {
-		var tableName, columnName, dataType string
-		if err := rows.Scan(&tableName, &columnName, &dataType); err != nil {
+		var tableName, referencedTable string
+		if err := rows.Scan(&tableName, &referencedTable); err != nil {
 			return nil, err
 		}
-
-		if tableName != currentTable {
-			if sb.Len() > 0 {
-				contexts = append(contexts, strings.TrimRight(sb.String(), ",\n")+"\n);")
-			}
-			sb.Reset()
-			sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", tableName))
-			currentTable = tableName
-		}
-
-		sb.WriteString(fmt.Sprintf("    %s %s,\n", columnName, dataType))
-	}
-
-	if sb.Len() > 0 {
-		contexts = append(contexts, strings.TrimRight(sb.String(), ",\n")+"\n);")
-	}
-
-	if len(contexts) == 0 {
-		return nil, fmt.Errorf("tidak ada tabel ditemukan di skema '%s'", schema)
+		graph[tableName] = append(graph[tableName], referencedTable)
 	}
-
-	log.Printf("✅ Berhasil! Mengambil %d potongan DDL dinamis.", len(contexts))
-	return contexts, nil
+	return graph, rows.Err()
 }
 
 func GetDynamicReferenceData(ctx context.Context) (string, error) {
-	targetTables := map[string]string{
-		"master_status_rekening": "nama_status",
-		"master_jenis_rekening":  "nama_jenis",
-		"master_tipe_nasabah":    "nama_tipe",
-		"master_tipe_transaksi":  "nama_transaksi",
-	}
-
 	if DbInstance == nil {
 		return "", fmt.Errorf("koneksi database belum siap")
 	}
@@ -112,39 +99,40 @@ func GetDynamicReferenceData(ctx context.Context) (string, error) {
 		return "", err
 	}
 
+	targetTables, err := ResolveReferenceTables(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	rowLimit := 50
+	if AppConfig != nil {
+		rowLimit = AppConfig.ReferenceTableRowLimit
+	}
+
 	var builder strings.Builder
 	builder.WriteString("== LIVE DATA REFERENSI (Isi Tabel Master Terbaru) ==\n")
 	builder.WriteString("Gunakan ID/Kode di bawah ini secara TEPAT jika user bertanya tentang kategori ini:\n\n")
 
-	for tableName, nameCol := range targetTables {
-		idCol := "id"
-		switch tableName {
-		case "master_status_rekening":
-			idCol = "id_status_rekening"
-		case "master_jenis_rekening":
-			idCol = "id_jenis_rekening"
-		case "master_tipe_nasabah":
-			idCol = "id_tipe_nasabah"
-		case "master_tipe_transaksi":
-			idCol = "id_tipe_transaksi"
-		}
-		query := fmt.Sprintf("SELECT %s, %s FROM %s.%s ORDER BY %s ASC", idCol, nameCol, schema, tableName, idCol)
+	for _, ref := range targetTables {
+		query := fmt.Sprintf("SELECT %s::text, %s::text FROM %s.%s ORDER BY %s ASC LIMIT %d",
+			quoteIdent(ref.IDColumn), quoteIdent(ref.LabelColumn), quoteIdent(schema), quoteIdent(ref.TableName),
+			quoteIdent(ref.IDColumn), rowLimit)
 
 		rows, err := DbInstance.QueryContext(ctx, query)
 		if err != nil {
-			log.Printf("Warning: Gagal ambil ref data untuk tabel %s: %v", tableName, err)
+			log.Printf("Warning: Gagal ambil ref data untuk tabel %s: %v", ref.TableName, err)
 			continue
 		}
 
-		builder.WriteString(fmt.Sprintf("TABEL REFERENSI: '%s'\n", tableName))
+		builder.WriteString(fmt.Sprintf("TABEL REFERENSI: '%s' (kolom ID: %s, label: %s)\n", ref.TableName, ref.IDColumn, ref.LabelColumn))
 
 		counter := 0
 		for rows.Next() {
-			var id, nama string
+			var id, nama sql.NullString
 			if err := rows.Scan(&id, &nama); err != nil {
 				continue
 			}
-			builder.WriteString(fmt.Sprintf("- ID '%s' = %s\n", id, nama))
+			builder.WriteString(fmt.Sprintf("- ID '%s' = %s\n", id.String, nama.String))
 			counter++
 		}
 		rows.Close()
@@ -164,21 +152,15 @@ func GetDynamicSqlExamples() ([]SqlExample, error) {
 		return nil, fmt.Errorf("koneksi database (Dbinstance) belum siap")
 	}
 
-	// Get schema name dynamically from connection string
-	schema, err := getSchemaFromConnStr()
-	if err != nil {
-		return nil, fmt.Errorf("gagal mendapatkan schema dari connection string: %w", err)
-	}
-
 	query := fmt.Sprintf(`
 	SELECT
 		prompt_example,
 		sql_example
 	FROM
-		%s.rag_sql_examples
+		%s
 	ORDER BY
 		id;
-	`, schema)
+	`, metaTable("rag_sql_examples"))
 
 	rows, err := DbInstance.QueryContext(context.Background(), query)
 	if err != nil {
@@ -214,25 +196,19 @@ func AddSqlExample(promptAsli string, sqlKoreksi string) error {
 		return fmt.Errorf("koneksi database (DbInstance) belum siap")
 	}
 
-	// Get schema name dynamically from connection string
-	schema, err := getSchemaFromConnStr()
-	if err != nil {
-		return fmt.Errorf("gagal mendapatkan schema dari connection string: %w", err)
-	}
-
 	promptExample := fmt.Sprintf("-- Pertanyaan: \"%s\"", promptAsli)
 
 	query := fmt.Sprintf(`
-	INSERT INTO %s.rag_sql_examples
+	INSERT INTO %s
 		(prompt_example, sql_example)
 	VALUES
 		($1, $2)
-	`, schema)
+	`, metaTable("rag_sql_examples"))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = DbInstance.ExecContext(ctx, query, promptExample, sqlKoreksi)
+	_, err := DbInstance.ExecContext(ctx, query, promptExample, sqlKoreksi)
 	if err != nil {
 		return fmt.Errorf("gagal insert contekan baru ke DB: %w", err)
 	}
@@ -240,74 +216,3 @@ func AddSqlExample(promptAsli string, sqlKoreksi string) error {
 	log.Printf("✅ Berhasil! Menyimpan contekan baru ke 'rag_sql_examples' untuk prompt: %s", promptAsli)
 	return nil
 }
-
-type DictionaryItem struct {
-	Istilah   string
-	Definisi  string
-	LogikaSQL string
-}
-
-func GetBusinessDictionary(ctx context.Context) (string, error) {
-	rows, err := DbInstance.QueryContext(ctx, "SELECT istilah, definisi_bisnis, logika_sql FROM ai_dictionary")
-	if err != nil {
-		return "", nil
-	}
-	defer rows.Close()
-
-	var builder strings.Builder
-	builder.WriteString("== KAMUS ISTILAH BISNIS (PRIORITAS TINGGI) ==\n")
-	builder.WriteString("Gunakan logika ini jika user menyebut kata kunci berikut:\n")
-
-	for rows.Next() {
-		var d DictionaryItem
-		if err := rows.Scan(&d.Istilah, &d.Definisi, &d.LogikaSQL); err != nil {
-			return "", err
-		}
-		line := fmt.Sprintf("- \"%s\" bermakna: %s. (SQL Logic Wajib: `%s`)\n", d.Istilah, d.Definisi, d.LogikaSQL)
-		builder.WriteString(line)
-	}
-
-	return builder.String(), nil
-}
-
-type AbsurdKeyword struct {
-	Keyword  string
-	Category string
-	IsActive bool
-}
-
-func IsAbsurdPrompt(ctx context.Context, prompt string) (bool, error) {
-	if DbInstance == nil {
-		return false, fmt.Errorf("koneksi database (DbInstance) belum siap")
-	}
-
-	schema, err := getSchemaFromConnStr()
-	if err != nil {
-		return false, fmt.Errorf("gagal mendapatkan schema: %w", err)
-	}
-
-	lowerPrompt := strings.ToLower(prompt)
-
-	query := fmt.Sprintf(`
-        SELECT EXISTS (
-            SELECT 1 
-            FROM %s.absurd_keywords 
-            WHERE is_active = true 
-              AND $1 ILIKE '%%' || keyword || '%%'
-            LIMIT 1
-        )
-    `, schema)
-
-	var exists bool
-	err = DbInstance.QueryRowContext(ctx, query, lowerPrompt).Scan(&exists)
-	if err != nil {
-		log.Printf("Error query absurd_keywords: %v", err)
-		return false, err
-	}
-
-	if exists {
-		log.Printf("Prompt terdeteksi absurd: '%s'", prompt)
-	}
-
-	return exists, nil
-}
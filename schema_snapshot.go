@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SchemaSnapshot adalah salinan in-memory dari semua konteks skema yang dipakai prompt.
+// Version adalah hash isi snapshot; berubah hanya jika DDL, data referensi, atau kamus berubah.
+type SchemaSnapshot struct {
+	Version       string
+	RefreshedAt   time.Time
+	Tables        []TableInfo
+	DDLs          []string
+	ForeignKeys   map[string][]string
+	ReferenceData string
+	Dictionary    []DictionaryItem
+}
+
+var (
+	schemaSnapshotMu sync.RWMutex
+	schemaSnapshot   *SchemaSnapshot
+
+	// schemaRefreshMu mencegah dua refresh berjalan bersamaan (interval, NOTIFY, admin).
+	schemaRefreshMu sync.Mutex
+)
+
+// CurrentSchemaSnapshot mengembalikan snapshot yang sedang aktif, memuatnya jika belum ada.
+func CurrentSchemaSnapshot(ctx context.Context) (*SchemaSnapshot, error) {
+	schemaSnapshotMu.RLock()
+	snap := schemaSnapshot
+	schemaSnapshotMu.RUnlock()
+	if snap != nil {
+		return snap, nil
+	}
+	return RefreshSchemaSnapshot(ctx)
+}
+
+// LoadedSchemaSnapshot mengembalikan snapshot aktif tanpa memuatnya (nil jika belum ada).
+func LoadedSchemaSnapshot() *SchemaSnapshot {
+	schemaSnapshotMu.RLock()
+	defer schemaSnapshotMu.RUnlock()
+	return schemaSnapshot
+}
+
+// CurrentSchemaVersion mengembalikan hash snapshot aktif, atau "" jika belum dimuat.
+func CurrentSchemaVersion() string {
+	schemaSnapshotMu.RLock()
+	defer schemaSnapshotMu.RUnlock()
+	if schemaSnapshot == nil {
+		return ""
+	}
+	return schemaSnapshot.Version
+}
+
+// RefreshSchemaSnapshot membaca ulang skema dari PostgreSQL dan mengganti snapshot aktif.
+func RefreshSchemaSnapshot(ctx context.Context) (*SchemaSnapshot, error) {
+	schemaRefreshMu.Lock()
+	defer schemaRefreshMu.Unlock()
+
+	tables, err := IntrospectSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ddls := make([]string, 0, len(tables))
+	for _, t := range tables {
+		ddls = append(ddls, renderTableDDL(t))
+	}
+
+	fkGraph, err := GetForeignKeyGraph(ctx)
+	if err != nil {
+		log.Printf("PERINGATAN: Gagal membaca relasi FK untuk snapshot: %v", err)
+	}
+
+	refData, err := GetDynamicReferenceData(ctx)
+	if err != nil {
+		log.Println("Warning: Gagal ambil data referensi:", err)
+		refData = "(Data referensi tidak tersedia)"
+	}
+
+	dictionary, err := LoadDictionary(ctx)
+	if err != nil {
+		log.Println("Warning: Gagal ambil dictionary:", err)
+	}
+
+	hasher := sha256.New()
+	hasher.Write([]byte(strings.Join(ddls, "\n")))
+	hasher.Write([]byte(refData))
+	hasher.Write([]byte(renderBusinessDictionary(dictionary)))
+
+	snap := &SchemaSnapshot{
+		Version:       hex.EncodeToString(hasher.Sum(nil))[:16],
+		RefreshedAt:   time.Now(),
+		Tables:        tables,
+		DDLs:          ddls,
+		ForeignKeys:   fkGraph,
+		ReferenceData: refData,
+		Dictionary:    dictionary,
+	}
+
+	schemaSnapshotMu.Lock()
+	previous := schemaSnapshot
+	schemaSnapshot = snap
+	schemaSnapshotMu.Unlock()
+
+	if previous == nil || previous.Version != snap.Version {
+		log.Printf("✅ Schema snapshot diperbarui: versi %s (%d tabel).", snap.Version, len(tables))
+	}
+	return snap, nil
+}
+
+// StartSchemaSnapshotRefresher memuat snapshot awal lalu memperbaruinya secara berkala
+// dan setiap kali ada NOTIFY di channel SCHEMA_NOTIFY_CHANNEL.
+func StartSchemaSnapshotRefresher(ctx context.Context) {
+	if _, err := RefreshSchemaSnapshot(ctx); err != nil {
+		log.Printf("PERINGATAN: Gagal memuat schema snapshot awal: %v", err)
+	}
+
+	if AppConfig.SchemaRefreshInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(AppConfig.SchemaRefreshInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if _, err := RefreshSchemaSnapshot(ctx); err != nil {
+						log.Printf("PERINGATAN: Refresh schema snapshot berkala gagal: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
+	if AppConfig.SchemaNotifyChannel != "" {
+		go listenSchemaChanges(ctx, AppConfig.SchemaNotifyChannel)
+	}
+}
+
+// listenSchemaChanges memakai koneksi pgx khusus untuk LISTEN, karena database/sql
+// tidak mendukung notifikasi. Koneksi dibuka ulang otomatis jika terputus.
+func listenSchemaChanges(ctx context.Context, channel string) {
+	for {
+		if err := listenSchemaChangesOnce(ctx, channel); err != nil {
+			log.Printf("PERINGATAN: LISTEN %s terputus: %v. Mencoba lagi dalam 30 detik...", channel, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+func listenSchemaChangesOnce(ctx context.Context, channel string) error {
+	conn, err := pgx.Connect(ctx, AppConfig.DBConnString)
+	if err != nil {
+		return fmt.Errorf("gagal membuka koneksi LISTEN: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("gagal LISTEN: %w", err)
+	}
+	log.Printf("✅ Mendengarkan perubahan skema di channel '%s'.", channel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Menerima NOTIFY '%s' (%s), refresh schema snapshot...", notification.Channel, notification.Payload)
+		if _, err := RefreshSchemaSnapshot(ctx); err != nil {
+			log.Printf("PERINGATAN: Refresh schema snapshot dari NOTIFY gagal: %v", err)
+		}
+	}
+}
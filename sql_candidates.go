@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sqlCandidate adalah satu sampel SQL dari LLM beserta hasil eksekusi read-only-nya.
+type sqlCandidate struct {
+	Index       int
+	Temperature float32
+	Output      LLMSQLOutput
+	LLM         llmCallResult
+	Result      QueryResult
+	Truncated   bool
+	Fingerprint string
+	Err         error
+}
+
+// VoteSummary merangkum voting berbasis hasil eksekusi antar kandidat SQL.
+type VoteSummary struct {
+	Candidates int     `json:"candidates"`
+	Valid      int     `json:"valid"`
+	Agreeing   int     `json:"agreeing"`
+	Clusters   int     `json:"clusters"`
+	Confidence float64 `json:"confidence"`
+}
+
+// sqlVoteOutcome adalah hasil voting: kandidat pemenang, atau klarifikasi jika mayoritas
+// kandidat meminta informasi tambahan.
+type sqlVoteOutcome struct {
+	Winner        *sqlCandidate
+	Clarification *ClarificationRequest
+	Summary       VoteSummary
+}
+
+// candidateCount menentukan jumlah sampel: permintaan per request (dibatasi SQL_CANDIDATES_MAX)
+// atau SQL_CANDIDATES.
+func candidateCount(requested int) int {
+	n := AppConfig.SQLCandidates
+	if requested > 0 {
+		n = requested
+	}
+	if AppConfig.SQLCandidatesMax > 0 && n > AppConfig.SQLCandidatesMax {
+		n = AppConfig.SQLCandidatesMax
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// generateSQLCandidates mengambil n sampel secara paralel. Kandidat ke-i memakai suhu dan
+// provider ke-(i mod panjang daftar) dari SQL_CANDIDATE_TEMPERATURES / SQL_CANDIDATE_PROVIDERS,
+// lalu setiap SQL dieksekusi read-only dengan batas SQL_CANDIDATE_MAX_ROWS baris.
+func generateSQLCandidates(ctx context.Context, prompt string, n int) []*sqlCandidate {
+	temps := AppConfig.SQLCandidateTemperatures
+	if len(temps) == 0 {
+		temps = []float32{0}
+	}
+	providers := AppConfig.SQLCandidateProviders
+
+	candidates := make([]*sqlCandidate, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		c := &sqlCandidate{Index: i, Temperature: temps[i%len(temps)]}
+		candidates[i] = c
+		provider := ""
+		if len(providers) > 0 {
+			provider = providers[i%len(providers)]
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Output, c.LLM, c.Err = generateLLMSQLOutput(ctx, prompt, c.Temperature, provider)
+			if c.Err != nil || c.Output.Clarification != nil {
+				return
+			}
+			if err := validateReadOnlySQL(c.Output.SQL); err != nil {
+				c.Err = err
+				return
+			}
+			c.Result, c.Truncated, c.Err = executeReadOnlyQuery(ctx, c.Output.SQL, nil, AppConfig.SQLCandidateMaxRows)
+			if c.Err == nil {
+				c.Fingerprint = resultFingerprint(c.Result.Rows, c.Truncated)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, c := range candidates {
+		status := "ok"
+		switch {
+		case c.Err != nil:
+			status = "gagal: " + c.Err.Error()
+		case c.Output.Clarification != nil:
+			status = "klarifikasi"
+		}
+		log.Printf("🗳️ Kandidat #%d (temp=%.2f, %s): %s", c.Index+1, c.Temperature, c.LLM.Provider, status)
+	}
+	return candidates
+}
+
+// resultFingerprint meng-hash result set tanpa memperhatikan urutan baris maupun nama kolom,
+// dengan normalisasi nilai yang sama seperti eval (sameResultSet).
+func resultFingerprint(rows [][]interface{}, truncated bool) string {
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		vals := make([]string, len(row))
+		for j, v := range row {
+			vals[j] = normalizeResultValue(v)
+		}
+		keys[i] = strings.Join(vals, "\x1f")
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "truncated=%t\x1e", truncated)
+	h.Write([]byte(strings.Join(keys, "\x1e")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// voteSQLCandidates mengelompokkan kandidat valid berdasarkan hasil eksekusi dan memilih
+// cluster terbesar (seri: total self-confidence LLM tertinggi, lalu kandidat paling awal).
+// Confidence = anggota cluster pemenang / seluruh kandidat.
+func voteSQLCandidates(candidates []*sqlCandidate) (sqlVoteOutcome, error) {
+	outcome := sqlVoteOutcome{Summary: VoteSummary{Candidates: len(candidates)}}
+
+	var clarifications []*sqlCandidate
+	clusters := make(map[string][]*sqlCandidate)
+	var order []string
+	var firstErr error
+	for _, c := range candidates {
+		switch {
+		case c.Err != nil:
+			if firstErr == nil {
+				firstErr = c.Err
+			}
+		case c.Output.Clarification != nil:
+			clarifications = append(clarifications, c)
+		default:
+			if _, ok := clusters[c.Fingerprint]; !ok {
+				order = append(order, c.Fingerprint)
+			}
+			clusters[c.Fingerprint] = append(clusters[c.Fingerprint], c)
+			outcome.Summary.Valid++
+		}
+	}
+	outcome.Summary.Clusters = len(clusters)
+
+	if len(clarifications)*2 > len(candidates) || (outcome.Summary.Valid == 0 && len(clarifications) > 0) {
+		outcome.Clarification = clarifications[0].Output.Clarification
+		outcome.Winner = clarifications[0]
+		outcome.Summary.Agreeing = len(clarifications)
+		outcome.Summary.Confidence = float64(len(clarifications)) / float64(len(candidates))
+		return outcome, nil
+	}
+	if outcome.Summary.Valid == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("tidak ada kandidat SQL yang valid")
+		}
+		return outcome, fmt.Errorf("semua kandidat SQL gagal: %w", firstErr)
+	}
+
+	confidenceSum := func(members []*sqlCandidate) float64 {
+		var sum float64
+		for _, m := range members {
+			sum += *m.Output.Confidence
+		}
+		return sum
+	}
+	best := order[0]
+	for _, fp := range order[1:] {
+		if len(clusters[fp]) > len(clusters[best]) ||
+			(len(clusters[fp]) == len(clusters[best]) && confidenceSum(clusters[fp]) > confidenceSum(clusters[best])) {
+			best = fp
+		}
+	}
+
+	members := clusters[best]
+	winner := members[0]
+	for _, m := range members[1:] {
+		if *m.Output.Confidence > *winner.Output.Confidence {
+			winner = m
+		}
+	}
+	outcome.Winner = winner
+	outcome.Summary.Agreeing = len(members)
+	outcome.Summary.Confidence = float64(len(members)) / float64(len(candidates))
+	log.Printf("🗳️ Voting: %d/%d kandidat sepakat (%d cluster), pemenang kandidat #%d.",
+		outcome.Summary.Agreeing, outcome.Summary.Candidates, outcome.Summary.Clusters, winner.Index+1)
+	return outcome, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCandidateCount(t *testing.T) {
+	prev := AppConfig
+	defer func() { AppConfig = prev }()
+
+	tests := []struct {
+		name       string
+		configured int
+		max        int
+		requested  int
+		want       int
+	}{
+		{"default dari config", 3, 5, 0, 3},
+		{"permintaan request", 1, 5, 4, 4},
+		{"dibatasi max", 1, 5, 9, 5},
+		{"max nol berarti tanpa batas", 1, 0, 9, 9},
+		{"minimal satu", 0, 5, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig = &Config{SQLCandidates: tt.configured, SQLCandidatesMax: tt.max}
+			if got := candidateCount(tt.requested); got != tt.want {
+				t.Errorf("candidateCount(%d) = %d, want %d", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultFingerprint(t *testing.T) {
+	a := resultFingerprint([][]interface{}{{1, "x"}, {2, "y"}}, false)
+	tests := []struct {
+		name      string
+		rows      [][]interface{}
+		truncated bool
+		same      bool
+	}{
+		{"urutan baris berbeda", [][]interface{}{{2, "y"}, {1, "x"}}, false, true},
+		{"representasi numerik berbeda", [][]interface{}{{[]byte("1.0"), "x"}, {int64(2), "y"}}, false, true},
+		{"nilai berbeda", [][]interface{}{{1, "x"}, {2, "z"}}, false, false},
+		{"terpotong dibedakan", [][]interface{}{{1, "x"}, {2, "y"}}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resultFingerprint(tt.rows, tt.truncated) == a; got != tt.same {
+				t.Errorf("fingerprint sama = %t, want %t", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestVoteSQLCandidates(t *testing.T) {
+	conf := func(v float64) *float64 { return &v }
+	valid := func(idx int, fp string, confidence float64) *sqlCandidate {
+		return &sqlCandidate{Index: idx, Fingerprint: fp, Output: LLMSQLOutput{SQL: "SELECT " + fp, Confidence: conf(confidence)}}
+	}
+	clarify := func(idx int) *sqlCandidate {
+		return &sqlCandidate{Index: idx, Output: LLMSQLOutput{
+			Confidence:    conf(0.2),
+			Clarification: &ClarificationRequest{Questions: []string{"periode?"}},
+		}}
+	}
+	failed := func(idx int) *sqlCandidate {
+		return &sqlCandidate{Index: idx, Err: errors.New("syntax error")}
+	}
+
+	tests := []struct {
+		name           string
+		candidates     []*sqlCandidate
+		wantWinner     int
+		wantClarify    bool
+		wantAgreeing   int
+		wantClusters   int
+		wantConfidence float64
+		wantErr        bool
+	}{
+		{
+			name:       "mayoritas menang",
+			candidates: []*sqlCandidate{valid(0, "a", 0.9), valid(1, "b", 0.8), valid(2, "b", 0.6)},
+			wantWinner: 1, wantAgreeing: 2, wantClusters: 2, wantConfidence: 2.0 / 3,
+		},
+		{
+			name:       "seri dipecah total confidence",
+			candidates: []*sqlCandidate{valid(0, "a", 0.5), valid(1, "b", 0.9)},
+			wantWinner: 1, wantAgreeing: 1, wantClusters: 2, wantConfidence: 0.5,
+		},
+		{
+			name:       "seri confidence sama: kandidat paling awal",
+			candidates: []*sqlCandidate{valid(0, "a", 0.7), valid(1, "b", 0.7)},
+			wantWinner: 0, wantAgreeing: 1, wantClusters: 2, wantConfidence: 0.5,
+		},
+		{
+			name:       "pemenang dalam cluster: confidence tertinggi",
+			candidates: []*sqlCandidate{valid(0, "a", 0.4), valid(1, "a", 0.8), failed(2)},
+			wantWinner: 1, wantAgreeing: 2, wantClusters: 1, wantConfidence: 2.0 / 3,
+		},
+		{
+			name:       "mayoritas meminta klarifikasi",
+			candidates: []*sqlCandidate{clarify(0), clarify(1), valid(2, "a", 0.9)},
+			wantWinner: 0, wantClarify: true, wantAgreeing: 2, wantClusters: 1, wantConfidence: 2.0 / 3,
+		},
+		{
+			name:       "tidak ada SQL valid, ada klarifikasi",
+			candidates: []*sqlCandidate{failed(0), failed(1), clarify(2)},
+			wantWinner: 2, wantClarify: true, wantAgreeing: 1, wantClusters: 0, wantConfidence: 1.0 / 3,
+		},
+		{
+			name:       "klarifikasi minoritas diabaikan",
+			candidates: []*sqlCandidate{clarify(0), valid(1, "a", 0.6), valid(2, "b", 0.5)},
+			wantWinner: 1, wantAgreeing: 1, wantClusters: 2, wantConfidence: 1.0 / 3,
+		},
+		{
+			name:       "semua gagal",
+			candidates: []*sqlCandidate{failed(0), failed(1)},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := voteSQLCandidates(tt.candidates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("voteSQLCandidates error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Winner == nil || got.Winner.Index != tt.wantWinner {
+				t.Errorf("pemenang = %+v, want kandidat #%d", got.Winner, tt.wantWinner)
+			}
+			if (got.Clarification != nil) != tt.wantClarify {
+				t.Errorf("klarifikasi = %+v, want ada=%t", got.Clarification, tt.wantClarify)
+			}
+			s := got.Summary
+			if s.Candidates != len(tt.candidates) || s.Agreeing != tt.wantAgreeing || s.Clusters != tt.wantClusters || s.Confidence != tt.wantConfidence {
+				t.Errorf("summary = %+v, want agreeing=%d clusters=%d confidence=%v", s, tt.wantAgreeing, tt.wantClusters, tt.wantConfidence)
+			}
+		})
+	}
+}
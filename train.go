@@ -2,128 +2,53 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"strings"
-	"time"
-
-	"github.com/google/generative-ai-go/genai"
-	"github.com/google/uuid"
-	"github.com/joho/godotenv"
-	"google.golang.org/api/option"
 )
 
-func mainTrain() {
+// RunTraining menyinkronkan pengetahuan RAG (DDL + contoh SQL) ke Qdrant memakai koneksi
+// DB & embedder yang sudah diinisialisasi. Error dikembalikan ke pemanggil, tidak menghentikan proses.
+func RunTraining(ctx context.Context, opts TrainOptions) (TrainResult, error) {
 	log.Println("Memulai proses Training Pengetahuan")
 
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("⚠️Error memuat .env: %v", err)
+	if AppConfig == nil {
+		return TrainResult{}, fmt.Errorf("konfigurasi aplikasi belum dimuat")
 	}
-
-	_, err := LoadConfig()
-	if err != nil {
-		log.Fatalf("⚠️Error memuat konfigurasi: %v", err)
-	}
-	log.Println("✅ Konfigurasi berhasil dimuat")
-
-	ctx := context.Background()
-
-	// Connect to database
-	if err := ConnectDB(); err != nil {
-		log.Fatalf("⚠️Gagal koneksi ke DB Postgres: %v", err)
-	}
-	log.Println("Koneksi DB Postgres untuk baca skema... OK.")
-
-	// Initialize Google AI client for embeddings
-	geminiClient, err := genai.NewClient(ctx, option.WithAPIKey(AppConfig.GoogleAPIKey))
-	if err != nil {
-		log.Fatalf("Gagal membuat client Gemini: %v", err)
+	if DbInstance == nil {
+		return TrainResult{}, fmt.Errorf("koneksi database (DbInstance) belum siap")
 	}
-	defer geminiClient.Close()
-	embedder := geminiClient.EmbeddingModel(AppConfig.EmbeddingModel)
-	log.Printf("agar bersih...", AppConfig.QdrantCollectionName)
-	if err := qdrantDeleteCollection(ctx, AppConfig.QdrantURL, AppConfig.QdrantCollectionName); err != nil {
-		log.Printf("Gagal menghapus collection (mungkin belum ada): %v", err)
-	}
-	time.Sleep(3 * time.Second)
-	log.Printf("Koneksi 'Penerjemah' (Google AI)... OK. Model: %s", AppConfig.EmbeddingModel)
-
-	// Create/recreate Qdrant collection
-	log.Printf("🆕 Membuat ulang koleksi '%s'...", AppConfig.QdrantCollectionName)
-	if err := qdrantCreateCollection(ctx, AppConfig.QdrantURL, AppConfig.QdrantCollectionName,
-		AppConfig.EmbeddingVectorSize, AppConfig.QdrantDistanceMetric); err != nil {
-		log.Fatalf("❌ Gagal membuat koleksi di Qdrant: %v", err)
+	if geminiEmbedder == nil {
+		return TrainResult{}, fmt.Errorf("service embedding belum diinisialisasi")
 	}
 
 	log.Println("Mulai 'melatih' (meng-embed dan menyimpan) contekan...")
 
-	dynamicDDLs, err := GetDynamicSchemaContext()
+	snap, err := RefreshSchemaSnapshot(ctx)
 	if err != nil {
-		log.Fatalf("Gagal mengambil DDL dinamis: %v", err)
+		return TrainResult{}, fmt.Errorf("gagal mengambil DDL dinamis: %w", err)
 	}
 
 	dynamicSQLExamples, err := GetDynamicSqlExamples()
 	if err != nil {
-		log.Fatalf("Gagal mengambil contoh SQL dinamis: %v", err)
-	}
-
-	var points []qdrantPoint
-
-	// A. PROSES DDL (Label: "ddl")
-	log.Printf("Memproses %d DDL...", len(dynamicDDLs))
-	for i, content := range dynamicDDLs {
-		res, err := embedder.EmbedContent(ctx, genai.Text(content))
-		if err != nil {
-			log.Printf("Skip DDL #%d: %v", i, err)
-			continue
-		}
-
-		point := qdrantPoint{
-			ID:     uuid.NewString(),
-			Vector: res.Embedding.Values,
-			Payload: map[string]interface{}{
-				"content":  content,
-				"category": "ddl",
-			},
-		}
-		points = append(points, point)
+		return TrainResult{}, fmt.Errorf("gagal mengambil contoh SQL dinamis: %w", err)
 	}
 
-	log.Printf("Memproses %d Contoh SQL...", len(dynamicSQLExamples))
-	for i, item := range dynamicSQLExamples {
-		cleanPrompt := item.PromptOnly
-		cleanPrompt = strings.Replace(cleanPrompt, "-- Pertanyaan: ", "", 1) // Hapus prefix
-		cleanPrompt = strings.Replace(cleanPrompt, "\"", "", -1)             // Hapus tanda kutip
-		cleanPrompt = strings.TrimSpace(cleanPrompt)
-		log.Printf("Embedding Prompt Bersih: '%s'", cleanPrompt)
+	items := buildRagItems(snap, dynamicSQLExamples)
+	log.Printf("Memproses %d DDL dan %d Contoh SQL...", len(snap.DDLs), len(dynamicSQLExamples))
 
-		res, err := embedder.EmbedContent(ctx, genai.Text(cleanPrompt)) // Asumsi struct baru
-		if err != nil {
-			log.Printf("Skip SQL #%d: %v", i, err)
-			continue
-		}
-
-		point := qdrantPoint{
-			ID:     uuid.NewString(),
-			Vector: res.Embedding.Values,
-			Payload: map[string]interface{}{
-				"content":        item.FullContent,
-				"prompt_preview": cleanPrompt,
-				"category":       "sql",
-			},
-		}
-		points = append(points, point)
+	result, err := SyncRAGCollection(ctx, GenerateEmbedding, items, opts)
+	if err != nil {
+		return result, fmt.Errorf("gagal sinkronisasi koleksi RAG: %w", err)
 	}
+	log.Printf("Ringkasan: %d item, %d di-embed, %d dipakai ulang, %d gagal, %d dihapus.",
+		result.Total, result.Embedded, result.Skipped, result.Failed, result.Removed)
 
-	log.Println("Menyimpan semua vektor ke Qdrant via REST...")
-	if len(points) == 0 {
-		log.Println("Tidak ada point untuk di-upsert (semua gagal embed?).")
-	} else {
-		if err := qdrantUpsertPoints(ctx, AppConfig.QdrantURL, AppConfig.QdrantCollectionName, points); err != nil {
-			log.Fatalf("Gagal menyimpan vektor ke Qdrant: %v", err)
-		}
+	if err := RefreshSparseIndex(); err != nil {
+		log.Printf("PERINGATAN: Gagal membangun ulang indeks BM25: %v", err)
 	}
 
 	log.Println("-----------------------------------------------")
 	log.Printf("✅ 'Training' selesai! Database Vektor '%s' sudah terisi (Dinamis).", AppConfig.QdrantCollectionName)
 	log.Println("-----------------------------------------------")
+	return result, nil
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TrainJobRunning   = "running"
+	TrainJobSucceeded = "succeeded"
+	TrainJobFailed    = "failed"
+	TrainJobCancelled = "cancelled"
+)
+
+var ErrTrainJobRunning = errors.New("retraining lain sedang berjalan")
+
+// TrainJob adalah satu proses retraining RAG yang dijalankan di background.
+type TrainJob struct {
+	ID          string      `json:"id"`
+	Status      string      `json:"status"`
+	Incremental bool        `json:"incremental"`
+	Progress    TrainResult `json:"progress"`
+	Error       string      `json:"error,omitempty"`
+	StartedAt   time.Time   `json:"started_at"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// trainJobHistoryLimit adalah jumlah job selesai yang tetap bisa dibaca lewat API status.
+const trainJobHistoryLimit = 20
+
+// TrainJobManager menyimpan riwayat job dan menjamin hanya satu retraining berjalan (single-flight).
+type TrainJobManager struct {
+	mu        sync.Mutex
+	jobs      map[string]*TrainJob
+	activeJob string
+	// historyLimit membatasi jumlah job selesai yang disimpan; yang paling lama dibuang lebih dulu.
+	historyLimit int
+}
+
+var trainJobs = &TrainJobManager{jobs: make(map[string]*TrainJob), historyLimit: trainJobHistoryLimit}
+
+// Start memulai job baru, atau mengembalikan job aktif beserta ErrTrainJobRunning.
+func (m *TrainJobManager) Start(opts TrainOptions) (TrainJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if active, ok := m.jobs[m.activeJob]; ok && active.Status == TrainJobRunning {
+		return *active, ErrTrainJobRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &TrainJob{
+		ID:          uuid.NewString(),
+		Status:      TrainJobRunning,
+		Incremental: opts.Incremental,
+		StartedAt:   time.Now(),
+		cancel:      cancel,
+	}
+	m.jobs[job.ID] = job
+	m.activeJob = job.ID
+
+	opts.Progress = func(progress TrainResult) {
+		m.mu.Lock()
+		job.Progress = progress
+		m.mu.Unlock()
+	}
+
+	go m.run(ctx, job, opts)
+	return *job, nil
+}
+
+func (m *TrainJobManager) run(ctx context.Context, job *TrainJob, opts TrainOptions) {
+	defer job.cancel()
+	log.Printf("ADMIN: training RAG (Embedding) dimulai, job %s", job.ID)
+
+	result, err := RunTraining(ctx, opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Progress = result
+	switch {
+	case err == nil:
+		job.Status = TrainJobSucceeded
+	case errors.Is(err, context.Canceled):
+		job.Status = TrainJobCancelled
+		job.Error = err.Error()
+	default:
+		job.Status = TrainJobFailed
+		job.Error = err.Error()
+	}
+	log.Printf("ADMIN: job retraining %s selesai dengan status %s", job.ID, job.Status)
+	m.pruneLocked()
+}
+
+// pruneLocked membuang job selesai tertua di atas historyLimit. Job yang masih berjalan tidak
+// pernah dibuang. Pemanggil harus memegang m.mu.
+func (m *TrainJobManager) pruneLocked() {
+	if m.historyLimit <= 0 {
+		return
+	}
+	finished := make([]*TrainJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= m.historyLimit {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.After(*finished[j].FinishedAt) })
+	for _, job := range finished[m.historyLimit:] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+// Running melaporkan apakah ada job retraining yang sedang berjalan.
+func (m *TrainJobManager) Running() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active, ok := m.jobs[m.activeJob]
+	return ok && active.Status == TrainJobRunning
+}
+
+// Get mengembalikan salinan status job.
+func (m *TrainJobManager) Get(id string) (TrainJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return TrainJob{}, false
+	}
+	return *job, true
+}
+
+// Cancel menghentikan job yang sedang berjalan. Mengembalikan false jika job tidak ditemukan.
+func (m *TrainJobManager) Cancel(id string) (TrainJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return TrainJob{}, false
+	}
+	if job.Status == TrainJobRunning {
+		job.cancel()
+	}
+	return *job, true
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTrainJobManagerPrune(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	finishedAt := func(i int) *time.Time {
+		at := base.Add(time.Duration(i) * time.Minute)
+		return &at
+	}
+
+	tests := []struct {
+		name     string
+		finished int
+		running  bool
+		limit    int
+		wantKept []string
+	}{
+		{"di bawah batas", 2, false, 3, []string{"job-0", "job-1"}},
+		{"yang tertua dibuang", 5, false, 2, []string{"job-3", "job-4"}},
+		{"job berjalan tidak dihitung", 3, true, 1, []string{"job-2", "running"}},
+		{"batas nol berarti tanpa batas", 3, false, 0, []string{"job-0", "job-1", "job-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &TrainJobManager{jobs: make(map[string]*TrainJob), historyLimit: tt.limit}
+			for i := 0; i < tt.finished; i++ {
+				id := fmt.Sprintf("job-%d", i)
+				m.jobs[id] = &TrainJob{ID: id, Status: TrainJobSucceeded, FinishedAt: finishedAt(i)}
+			}
+			if tt.running {
+				m.jobs["running"] = &TrainJob{ID: "running", Status: TrainJobRunning}
+			}
+
+			m.pruneLocked()
+
+			if len(m.jobs) != len(tt.wantKept) {
+				t.Errorf("jumlah job = %d, want %d", len(m.jobs), len(tt.wantKept))
+			}
+			for _, id := range tt.wantKept {
+				if _, ok := m.jobs[id]; !ok {
+					t.Errorf("job %s terbuang", id)
+				}
+			}
+		})
+	}
+}
@@ -35,7 +35,7 @@ SELECT jt.id_rekening, t.waktu_transaksi, t.deskripsi,
 CASE WHEN jt.tipe_dk = 'DEBIT' THEN jt.jumlah ELSE 0 END AS debit,
 CASE WHEN jt.tipe_dk = 'KREDIT' THEN jt.jumlah ELSE 0 END AS kredit,
 SUM(CASE WHEN jt.tipe_dk = 'KREDIT' THEN jt.jumlah ELSE -jt.jumlah END) OVER (PARTITION BY jt.id_rekening ORDER BY t.waktu_transaksi, t.id_transaksi) AS saldo_akhir
-FROM jurnal_transaksi jt JOIN transaksi t ON t.id_transaksi = t.id_transaksi
+FROM jurnal_transaksi jt JOIN transaksi t ON jt.id_transaksi = t.id_transaksi
 WHERE jt.id_rekening = '110000001'
 ORDER BY jt.id_rekening, t.waktu_transaksi, t.id_transaksi;
 `,
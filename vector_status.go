@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrVectorDegraded dikembalikan pemanggilan Qdrant di jalur query selama mode degraded,
+// tanpa menunggu timeout ke Qdrant yang sedang tidak tersedia.
+var ErrVectorDegraded = errors.New("layanan vektor (Qdrant) tidak tersedia, mode degraded")
+
+// VectorServiceStatus adalah kondisi koneksi Qdrant untuk output health.
+type VectorServiceStatus struct {
+	Status    string     `json:"status"` // "ok" atau "degraded"
+	Reason    string     `json:"reason,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	LastProbe *time.Time `json:"last_probe,omitempty"`
+}
+
+// vectorTimeoutThreshold adalah jumlah timeout Qdrant beruntun sebelum layanan masuk mode
+// degraded; Qdrant yang hang tidak menolak koneksi sehingga hanya terlihat dari timeout.
+const vectorTimeoutThreshold = 3
+
+var vectorState struct {
+	mu        sync.RWMutex
+	degraded  bool
+	reason    string
+	since     time.Time
+	lastProbe time.Time
+	timeouts  int
+}
+
+// VectorServiceDegraded bernilai true selama Qdrant dianggap tidak tersedia. Pipeline query
+// melewati semantic cache dan memakai indeks contoh lokal (BM25) sebagai pengganti RAG.
+func VectorServiceDegraded() bool {
+	vectorState.mu.RLock()
+	defer vectorState.mu.RUnlock()
+	return vectorState.degraded
+}
+
+func CurrentVectorServiceStatus() VectorServiceStatus {
+	vectorState.mu.RLock()
+	defer vectorState.mu.RUnlock()
+	s := VectorServiceStatus{Status: "ok"}
+	if vectorState.degraded {
+		since := vectorState.since
+		s.Status = "degraded"
+		s.Reason = vectorState.reason
+		s.Since = &since
+	}
+	if !vectorState.lastProbe.IsZero() {
+		probe := vectorState.lastProbe
+		s.LastProbe = &probe
+	}
+	return s
+}
+
+func markVectorDegraded(err error) {
+	vectorState.mu.Lock()
+	defer vectorState.mu.Unlock()
+	if vectorState.degraded {
+		return
+	}
+	vectorState.degraded = true
+	vectorState.reason = err.Error()
+	vectorState.since = time.Now()
+	log.Printf("⚠️ Qdrant tidak tersedia, masuk mode degraded (tanpa semantic cache, contoh SQL dari indeks lokal): %v", err)
+}
+
+func markVectorHealthy() {
+	vectorState.mu.Lock()
+	defer vectorState.mu.Unlock()
+	if !vectorState.degraded {
+		return
+	}
+	log.Printf("✅ Qdrant kembali tersedia setelah %s, keluar dari mode degraded.", time.Since(vectorState.since).Round(time.Second))
+	vectorState.degraded = false
+	vectorState.reason = ""
+	vectorState.timeouts = 0
+}
+
+// isVectorUnavailable membedakan Qdrant yang tidak bisa dihubungi (koneksi ditolak, gRPC
+// Unavailable, circuit breaker terbuka) dari error permintaan biasa.
+func isVectorUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.Is(err, ErrCircuitOpen) ||
+		status.Code(err) == codes.Unavailable ||
+		errors.As(err, &opErr)
+}
+
+// isVectorTimeout bernilai true jika Qdrant tidak menjawab sebelum deadline panggilan.
+func isVectorTimeout(err error) bool {
+	return err != nil && (errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded)
+}
+
+// noteVectorResult dipanggil setelah setiap panggilan Qdrant di jalur query. Layanan masuk mode
+// degraded jika err menunjukkan Qdrant tidak tersedia, atau setelah vectorTimeoutThreshold
+// timeout beruntun; jawaban apa pun dari Qdrant (termasuk error biasa) mereset hitungan timeout.
+func noteVectorResult(err error) {
+	if isVectorUnavailable(err) {
+		markVectorDegraded(err)
+		return
+	}
+
+	vectorState.mu.Lock()
+	if !isVectorTimeout(err) {
+		vectorState.timeouts = 0
+		vectorState.mu.Unlock()
+		return
+	}
+	vectorState.timeouts++
+	timeouts := vectorState.timeouts
+	vectorState.mu.Unlock()
+
+	if timeouts >= vectorTimeoutThreshold {
+		markVectorDegraded(fmt.Errorf("%d timeout beruntun: %w", timeouts, err))
+	}
+}
+
+// connectQdrant memastikan Qdrant bisa dihubungi dan collection cache tersedia.
+func connectQdrant(ctx context.Context) error {
+	if qdrantClient == nil {
+		return errors.New("Qdrant gRPC client belum dibuat")
+	}
+	if _, err := qdrantClient.HealthCheck(ctx); err != nil {
+		return fmt.Errorf("health check gRPC gagal: %w", err)
+	}
+	if err := qdrantCreateCollection(ctx, AppConfig.QdrantURL, AppConfig.QdrantCacheCollection,
+		AppConfig.EmbeddingVectorSize, AppConfig.QdrantDistanceMetric); err != nil {
+		return fmt.Errorf("gagal membuat/memverifikasi cache collection: %w", err)
+	}
+	return nil
+}
+
+// StartVectorReconnector mencoba menghubungkan ulang Qdrant setiap QDRANT_RECONNECT_INTERVAL_SECONDS
+// selama mode degraded; begitu berhasil, pipeline kembali memakai semantic cache dan RAG Qdrant.
+func StartVectorReconnector(ctx context.Context) {
+	interval := AppConfig.QdrantReconnectInterval
+	if interval <= 0 {
+		log.Println("Reconnect otomatis Qdrant dimatikan (QDRANT_RECONNECT_INTERVAL_SECONDS=0).")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !VectorServiceDegraded() {
+				continue
+			}
+
+			probeCtx, cancel := context.WithTimeout(ctx, interval)
+			err := connectQdrant(probeCtx)
+			cancel()
+
+			vectorState.mu.Lock()
+			vectorState.lastProbe = time.Now()
+			if err != nil {
+				vectorState.reason = err.Error()
+			}
+			vectorState.mu.Unlock()
+
+			if err != nil {
+				log.Printf("Qdrant masih belum tersedia: %v", err)
+				continue
+			}
+			markVectorHealthy()
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resetVectorState mengembalikan status layanan vektor ke kondisi sehat untuk satu test.
+func resetVectorState(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		vectorState.mu.Lock()
+		vectorState.degraded = false
+		vectorState.reason = ""
+		vectorState.timeouts = 0
+		vectorState.mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestIsVectorUnavailable(t *testing.T) {
+	connRefused := &url.Error{
+		Op:  "Post",
+		URL: "http://localhost:6333/collections/cache/points/search",
+		Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"circuit breaker terbuka", fmt.Errorf("%w untuk qdrant", ErrCircuitOpen), true},
+		{"koneksi ditolak di dalam url.Error", fmt.Errorf("gagal call: %w", connRefused), true},
+		{"gRPC Unavailable", status.Error(codes.Unavailable, "connection refused"), true},
+		{"gRPC DeadlineExceeded sekali", status.Error(codes.DeadlineExceeded, "deadline"), false},
+		{"gRPC NotFound", status.Error(codes.NotFound, "collection tidak ada"), false},
+		{"error biasa", errors.New("status HTTP 400"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isVectorUnavailable(tt.err); got != tt.want {
+				t.Errorf("isVectorUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteVectorResultTransitions(t *testing.T) {
+	grpcTimeout := status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	restTimeout := fmt.Errorf("gagal call: %w", &url.Error{Op: "Post", URL: "http://qdrant", Err: context.DeadlineExceeded})
+
+	tests := []struct {
+		name         string
+		results      []error
+		wantDegraded bool
+	}{
+		{"sukses tetap sehat", []error{nil, nil}, false},
+		{"Unavailable langsung degraded", []error{status.Error(codes.Unavailable, "down")}, true},
+		{"circuit breaker terbuka langsung degraded", []error{ErrCircuitOpen}, true},
+		{"timeout di bawah ambang", []error{grpcTimeout, grpcTimeout}, false},
+		{"timeout beruntun mencapai ambang", []error{grpcTimeout, restTimeout, grpcTimeout}, true},
+		{"sukses mereset hitungan timeout", []error{grpcTimeout, grpcTimeout, nil, grpcTimeout, grpcTimeout}, false},
+		{"error biasa mereset hitungan timeout", []error{grpcTimeout, grpcTimeout, errors.New("status HTTP 400"), grpcTimeout}, false},
+		{"dibatalkan pemanggil tidak dihitung timeout", []error{context.Canceled, context.Canceled, context.Canceled}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVectorState(t)
+			for _, err := range tt.results {
+				noteVectorResult(err)
+			}
+			if got := VectorServiceDegraded(); got != tt.wantDegraded {
+				t.Errorf("VectorServiceDegraded = %v, want %v", got, tt.wantDegraded)
+			}
+		})
+	}
+}
+
+func TestVectorDegradedAndRecovered(t *testing.T) {
+	resetVectorState(t)
+
+	noteVectorResult(status.Error(codes.Unavailable, "connection refused"))
+	s := CurrentVectorServiceStatus()
+	if s.Status != "degraded" || s.Since == nil || s.Reason == "" {
+		t.Fatalf("status setelah Unavailable = %+v, want degraded dengan reason & since", s)
+	}
+
+	// Error lain selama degraded tidak mengubah waktu mulai degraded
+	since := *s.Since
+	noteVectorResult(ErrCircuitOpen)
+	if got := CurrentVectorServiceStatus(); !got.Since.Equal(since) {
+		t.Errorf("since berubah dari %s ke %s", since, got.Since)
+	}
+
+	markVectorHealthy()
+	s = CurrentVectorServiceStatus()
+	if s.Status != "ok" || s.Since != nil || s.Reason != "" {
+		t.Errorf("status setelah pulih = %+v, want ok tanpa reason", s)
+	}
+	if VectorServiceDegraded() {
+		t.Error("VectorServiceDegraded masih true setelah pulih")
+	}
+}